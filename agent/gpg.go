@@ -0,0 +1,61 @@
+package agent
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// FlushGPG makes the gpg-agent listening on socket forget all cached
+// passphrases, like `gpg-connect-agent reloadagent /bye` does.
+// If socket is empty it is looked up with gpgconf.
+func FlushGPG(socket string) error {
+	if socket == "" {
+		out, err := exec.Command("gpgconf", "--list-dirs", "agent-socket").Output()
+		if err != nil {
+			return fmt.Errorf("could not find gpg-agent socket: %s", err)
+		}
+		socket = strings.TrimSpace(string(out))
+	}
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+
+	r := bufio.NewReader(conn)
+	// the agent greets us with an OK line
+	if err := assuanResponse(r); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(conn, "RELOADAGENT\n"); err != nil {
+		return err
+	}
+	if err := assuanResponse(r); err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(conn, "BYE\n")
+	return err
+}
+
+// assuanResponse reads lines until the final OK or ERR of a command
+func assuanResponse(r *bufio.Reader) error {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return err
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "OK" || strings.HasPrefix(line, "OK "):
+			return nil
+		case strings.HasPrefix(line, "ERR "):
+			return fmt.Errorf("gpg-agent: %s", line[4:])
+		}
+		// status and comment lines
+	}
+}
@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	sshagent "golang.org/x/crypto/ssh/agent"
+)
+
+// dialTimeout limits how long we wait for an agent socket
+const dialTimeout = 2 * time.Second
+
+// FlushSSH removes all identities from the ssh-agent listening on socket.
+// If socket is empty $SSH_AUTH_SOCK is used.
+func FlushSSH(socket string) error {
+	if socket == "" {
+		socket = os.Getenv("SSH_AUTH_SOCK")
+	}
+	if socket == "" {
+		return fmt.Errorf("no ssh-agent socket: SSH_AUTH_SOCK is not set")
+	}
+	conn, err := net.DialTimeout("unix", socket, dialTimeout)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(dialTimeout))
+	return sshagent.NewClient(conn).RemoveAll()
+}
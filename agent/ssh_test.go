@@ -0,0 +1,66 @@
+package agent
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	sshagent "golang.org/x/crypto/ssh/agent"
+)
+
+func TestFlushSSH(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gllock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "agent.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	keyring := sshagent.NewKeyring()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := keyring.Add(sshagent.AddedKey{PrivateKey: key, Comment: "test"}); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				sshagent.ServeAgent(keyring, conn)
+			}()
+		}
+	}()
+
+	if err := FlushSSH(socket); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := keyring.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 0 {
+		t.Errorf("agent still holds %d identities", len(keys))
+	}
+}
+
+func TestFlushSSHNoSocket(t *testing.T) {
+	os.Unsetenv("SSH_AUTH_SOCK")
+	if err := FlushSSH(""); err == nil {
+		t.Error("expected an error without SSH_AUTH_SOCK")
+	}
+}
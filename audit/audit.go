@@ -0,0 +1,202 @@
+package audit
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"sync"
+	"time"
+
+	"github.com/moolen/gllock/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Type is the kind of a security event
+type Type string
+
+const (
+	// LockStarted is emitted when gllock starts locking the screen
+	LockStarted Type = "lock-started"
+	// GrabAcquired is emitted once keyboard and pointer are grabbed
+	GrabAcquired Type = "grab-acquired"
+	// AuthFailure is emitted after every wrong password
+	AuthFailure Type = "auth-failure"
+	// Lockout is emitted when too many attempts failed
+	Lockout Type = "lockout"
+	// GrabLost is emitted when another client broke the input grab
+	GrabLost Type = "grab-lost"
+	// Unlocked is emitted when the password was accepted
+	Unlocked Type = "unlocked"
+	// Aborted is emitted when the lock screen was closed
+	// without a password, e.g. on SIGINT
+	Aborted Type = "aborted"
+	// SelectionsCleared is emitted when CLIPBOARD and PRIMARY were cleared
+	SelectionsCleared Type = "selections-cleared"
+	// SSHAgentFlushed is emitted when all ssh-agent identities were removed
+	SSHAgentFlushed Type = "ssh-agent-flushed"
+	// GPGAgentFlushed is emitted when the gpg-agent cache was cleared
+	GPGAgentFlushed Type = "gpg-agent-flushed"
+)
+
+// Event is a single audit record.
+// It deliberately has no field that could carry password material.
+type Event struct {
+	Type     Type      `json:"type"`
+	Time     time.Time `json:"time"`
+	User     string    `json:"user"`
+	Host     string    `json:"host"`
+	PID      int       `json:"pid"`
+	Attempts int       `json:"attempts,omitempty"`
+	// Error is set if the step the event stands for failed
+	Error string `json:"error,omitempty"`
+	// Reason tells why the lock was aborted
+	Reason string `json:"reason,omitempty"`
+}
+
+// Message returns a human readable description of the event
+func (e Event) Message() string {
+	if e.Error != "" {
+		return fmt.Sprintf("%s failed for %s: %s", e.Type, e.User, e.Error)
+	}
+	switch e.Type {
+	case AuthFailure:
+		return fmt.Sprintf("authentication failure for %s (attempt %d)", e.User, e.Attempts)
+	case Lockout:
+		return fmt.Sprintf("locked out %s after %d failed attempts", e.User, e.Attempts)
+	case Aborted:
+		return fmt.Sprintf("lock of %s aborted: %s", e.User, e.Reason)
+	default:
+		return fmt.Sprintf("%s: %s", e.Type, e.User)
+	}
+}
+
+// Sink receives audit events
+type Sink interface {
+	Write(Event) error
+	Close() error
+}
+
+// Log fans out events to all sinks.
+// Events are written from a separate goroutine,
+// so Emit never blocks the caller on I/O.
+type Log struct {
+	sinks  []Sink
+	events chan Event
+	done   chan struct{}
+	user   string
+	host   string
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// New starts a Log that writes to the given sinks
+func New(sinks ...Sink) *Log {
+	l := &Log{
+		sinks:  sinks,
+		events: make(chan Event, 64),
+		done:   make(chan struct{}),
+		user:   currentUser(),
+	}
+	l.host, _ = os.Hostname()
+	go l.run()
+	return l
+}
+
+// NewFromConfig creates all sinks enabled in the audit section of the config
+func NewFromConfig(cfg config.Audit) (*Log, error) {
+	var sinks []Sink
+	if cfg.Journal {
+		sink, err := NewJournal()
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.Syslog != nil {
+		sink, err := NewSyslog(cfg.Syslog.Network, cfg.Syslog.Address)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.File != "" {
+		sink, err := NewFile(cfg.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if cfg.Webhook != nil {
+		sinks = append(sinks, NewWebhook(cfg.Webhook.URL, cfg.Webhook.Headers, time.Duration(cfg.Webhook.Timeout)))
+	}
+	return New(sinks...), nil
+}
+
+// Emit records an event of the given type.
+// attempts is the number of failed attempts so far.
+func (l *Log) Emit(t Type, attempts int) {
+	l.emit(Event{Type: t, Attempts: attempts})
+}
+
+// Fail records that the step of the given type failed
+func (l *Log) Fail(t Type, err error) {
+	l.emit(Event{Type: t, Error: err.Error()})
+}
+
+// Abort records that the lock screen was closed without a password
+func (l *Log) Abort(reason string) {
+	l.emit(Event{Type: Aborted, Reason: reason})
+}
+
+func (l *Log) emit(ev Event) {
+	ev.Time = time.Now()
+	ev.User = l.user
+	ev.Host = l.host
+	ev.PID = os.Getpid()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		log.Debugf("audit log closed, dropping %s event", ev.Type)
+		return
+	}
+	select {
+	case l.events <- ev:
+	default:
+		log.Warnf("audit queue full, dropping %s event", ev.Type)
+	}
+}
+
+// Close writes all pending events and closes the sinks.
+// Events emitted afterwards are dropped.
+func (l *Log) Close() {
+	l.mu.Lock()
+	l.closed = true
+	close(l.events)
+	l.mu.Unlock()
+	<-l.done
+	for _, sink := range l.sinks {
+		if err := sink.Close(); err != nil {
+			log.Warnf("error closing audit sink: %s", err)
+		}
+	}
+}
+
+func (l *Log) run() {
+	defer close(l.done)
+	for ev := range l.events {
+		for _, sink := range l.sinks {
+			if err := sink.Write(ev); err != nil {
+				log.Warnf("error writing audit event: %s", err)
+			}
+		}
+	}
+}
+
+func currentUser() string {
+	u, err := user.Current()
+	if err != nil {
+		return fmt.Sprintf("uid=%d", os.Getuid())
+	}
+	return u.Username
+}
@@ -0,0 +1,186 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listen creates a unixgram socket in a temporary directory
+func listen(t *testing.T) (*net.UnixConn, string) {
+	dir, err := ioutil.TempDir("", "gllock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// receive reads a single datagram
+func receive(t *testing.T, conn *net.UnixConn) []byte {
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf[:n]
+}
+
+// parseJournal decodes a datagram of the journald native protocol
+func parseJournal(t *testing.T, data []byte) map[string]string {
+	fields := map[string]string{}
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, '\n')
+		if end < 0 {
+			t.Fatalf("unterminated field %q", data)
+		}
+		line := string(data[:end])
+		data = data[end+1:]
+		if i := strings.IndexByte(line, '='); i >= 0 {
+			fields[line[:i]] = line[i+1:]
+			continue
+		}
+		if len(data) < 8 {
+			t.Fatalf("missing length of field %s", line)
+		}
+		size := binary.LittleEndian.Uint64(data)
+		data = data[8:]
+		if uint64(len(data)) < size+1 || data[size] != '\n' {
+			t.Fatalf("bad binary field %s", line)
+		}
+		fields[line] = string(data[:size])
+		data = data[size+1:]
+	}
+	return fields
+}
+
+func TestJournal(t *testing.T) {
+	conn, path := listen(t)
+	journal, err := dialJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer journal.Close()
+
+	for _, ev := range []Event{
+		{Type: AuthFailure, User: "alice", Attempts: 2},
+		{Type: SSHAgentFlushed, User: "alice", Error: "agent refused operation\nsecond line"},
+		{Type: Aborted, User: "alice", Reason: "received signal interrupt"},
+	} {
+		if err := journal.Write(ev); err != nil {
+			t.Fatal(err)
+		}
+		fields := parseJournal(t, receive(t, conn))
+		want := map[string]string{
+			"MESSAGE":           ev.Message(),
+			"SYSLOG_IDENTIFIER": "gllock",
+			"GLLOCK_EVENT":      string(ev.Type),
+			"GLLOCK_USER":       ev.User,
+		}
+		for name, value := range want {
+			if fields[name] != value {
+				t.Errorf("%s: %s is %q, want %q", ev.Type, name, fields[name], value)
+			}
+		}
+		if fields["GLLOCK_REASON"] != ev.Reason {
+			t.Errorf("%s: GLLOCK_REASON is %q, want %q", ev.Type, fields["GLLOCK_REASON"], ev.Reason)
+		}
+		if fields["PRIORITY"] != "4" {
+			t.Errorf("%s: PRIORITY is %q, want 4", ev.Type, fields["PRIORITY"])
+		}
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	conn, path := listen(t)
+	sink, err := NewSyslog("unixgram", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sink.Close()
+
+	ev := Event{Type: Lockout, User: "alice", Attempts: 3}
+	if err := sink.Write(ev); err != nil {
+		t.Fatal(err)
+	}
+	msg := string(receive(t, conn))
+	// LOG_AUTH (4<<3) with LOG_ERR (3)
+	if !strings.HasPrefix(msg, "<35>") {
+		t.Errorf("message %q does not have priority <35>", msg)
+	}
+	if !strings.Contains(msg, "gllock") || !strings.Contains(msg, ev.Message()) {
+		t.Errorf("message %q does not contain tag and %q", msg, ev.Message())
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	received := make(chan Event, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected content type %q", r.Header.Get("Content-Type"))
+		}
+		var ev Event
+		if err := json.NewDecoder(r.Body).Decode(&ev); err != nil {
+			t.Error(err)
+		}
+		received <- ev
+	}))
+	defer server.Close()
+
+	ev := Event{Type: Unlocked, User: "alice", Host: "box", PID: 42}
+	hook := NewWebhook(server.URL, map[string]string{"Authorization": "Bearer secret"}, 0)
+	if err := hook.Write(ev); err != nil {
+		t.Fatal(err)
+	}
+	got := <-received
+	if got.Type != ev.Type || got.User != ev.User || got.Host != ev.Host || got.PID != ev.PID {
+		t.Errorf("received %+v, want %+v", got, ev)
+	}
+
+	unauthorized := NewWebhook(server.URL, nil, 0)
+	if err := unauthorized.Write(ev); err == nil {
+		t.Error("expected an error for a non-2xx response")
+	}
+}
+
+func TestLog(t *testing.T) {
+	conn, path := listen(t)
+	journal, err := dialJournal(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := New(journal)
+	l.Emit(LockStarted, 0)
+	l.Fail(GPGAgentFlushed, errors.New("no agent"))
+	l.Close()
+	// events after Close are dropped instead of panicking
+	l.Emit(Unlocked, 0)
+
+	for _, want := range []Type{LockStarted, GPGAgentFlushed} {
+		fields := parseJournal(t, receive(t, conn))
+		if fields["GLLOCK_EVENT"] != string(want) {
+			t.Errorf("GLLOCK_EVENT is %q, want %q", fields["GLLOCK_EVENT"], want)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"encoding/json"
+	"os"
+)
+
+// File appends events as JSON lines to a file
+type File struct {
+	file *os.File
+	enc  *json.Encoder
+}
+
+// NewFile opens path for appending, creating it if necessary
+func NewFile(path string) (*File, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &File{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+// Write appends the event as a single line
+func (f *File) Write(ev Event) error {
+	return f.enc.Encode(ev)
+}
+
+// Close closes the file
+func (f *File) Close() error {
+	return f.file.Close()
+}
@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const journalSocket = "/run/systemd/journal/socket"
+
+// Journal writes events to journald using its native protocol
+type Journal struct {
+	conn *net.UnixConn
+}
+
+// NewJournal connects to the journald socket
+func NewJournal() (*Journal, error) {
+	return dialJournal(journalSocket)
+}
+
+func dialJournal(path string) (*Journal, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{conn: conn}, nil
+}
+
+// Write sends the event as a structured journal entry
+func (j *Journal) Write(ev Event) error {
+	var buf bytes.Buffer
+	writeField(&buf, "MESSAGE", ev.Message())
+	writeField(&buf, "PRIORITY", fmt.Sprint(priority(ev)))
+	writeField(&buf, "SYSLOG_IDENTIFIER", "gllock")
+	writeField(&buf, "GLLOCK_EVENT", string(ev.Type))
+	writeField(&buf, "GLLOCK_USER", ev.User)
+	writeField(&buf, "GLLOCK_ATTEMPTS", fmt.Sprint(ev.Attempts))
+	if ev.Reason != "" {
+		writeField(&buf, "GLLOCK_REASON", ev.Reason)
+	}
+	_, err := j.conn.Write(buf.Bytes())
+	return err
+}
+
+// writeField appends a single field. Values containing a newline,
+// e.g. an error message, use the binary encoding of the protocol:
+// the name, a newline, the length as 64 bit little endian and the value.
+func writeField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// Close closes the connection to journald
+func (j *Journal) Close() error {
+	return j.conn.Close()
+}
+
+// priority maps an event to a syslog priority
+func priority(ev Event) int {
+	if ev.Error != "" {
+		return 4 // warning
+	}
+	switch ev.Type {
+	case AuthFailure, GrabLost, Aborted:
+		return 4 // warning
+	case Lockout:
+		return 3 // error
+	default:
+		return 5 // notice
+	}
+}
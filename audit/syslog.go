@@ -0,0 +1,37 @@
+package audit
+
+import (
+	"log/syslog"
+)
+
+// Syslog writes events to a syslog daemon
+type Syslog struct {
+	w *syslog.Writer
+}
+
+// NewSyslog connects to the syslog daemon at address.
+// If network is empty the local syslog socket is used.
+func NewSyslog(network, address string) (*Syslog, error) {
+	w, err := syslog.Dial(network, address, syslog.LOG_NOTICE|syslog.LOG_AUTH, "gllock")
+	if err != nil {
+		return nil, err
+	}
+	return &Syslog{w: w}, nil
+}
+
+// Write logs the event message with a priority matching the event
+func (s *Syslog) Write(ev Event) error {
+	switch priority(ev) {
+	case 3:
+		return s.w.Err(ev.Message())
+	case 4:
+		return s.w.Warning(ev.Message())
+	default:
+		return s.w.Notice(ev.Message())
+	}
+}
+
+// Close closes the connection to the syslog daemon
+func (s *Syslog) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Webhook posts every event as JSON to an HTTP endpoint
+type Webhook struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+// NewWebhook creates a Webhook that posts to url.
+// headers are added to every request, e.g. for authorization.
+func NewWebhook(url string, headers map[string]string, timeout time.Duration) *Webhook {
+	if timeout == 0 {
+		timeout = 5 * time.Second
+	}
+	return &Webhook{
+		url:     url,
+		headers: headers,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Write posts the event and checks for a 2xx response
+func (w *Webhook) Write(ev Event) error {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("webhook %s returned %s", w.url, res.Status)
+	}
+	return nil
+}
+
+// Close is a no-op
+func (w *Webhook) Close() error {
+	return nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// authState is what the effects know about the password prompt
+type authState struct {
+	// attempts is the number of failed attempts
+	attempts int
+	// lastKey, lastFailure and unlocked are zero if they never happened
+	lastKey     time.Time
+	lastFailure time.Time
+	unlocked    time.Time
+}
+
+// since returns the seconds between t and now, -1 if t is zero
+func since(t, now time.Time) float32 {
+	if t.IsZero() {
+		return -1
+	}
+	return float32(now.Sub(t).Seconds())
+}
+
+// authTracker records the events of the password matcher, or the fake
+// events of the preview. It is safe for concurrent use.
+type authTracker struct {
+	mu    sync.Mutex
+	state authState
+}
+
+// key records a key press
+func (a *authTracker) key() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state.lastKey = time.Now()
+}
+
+// fail records a failed attempt
+func (a *authTracker) fail() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state.attempts++
+	a.state.lastFailure = time.Now()
+}
+
+// unlock records the successful attempt
+func (a *authTracker) unlock() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state.unlocked = time.Now()
+}
+
+// reset forgets all events
+func (a *authTracker) reset() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.state = authState{}
+}
+
+// snapshot returns the current state
+func (a *authTracker) snapshot() authState {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.state
+}
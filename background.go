@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"os"
+	"strconv"
+	"strings"
+
+	// decode background images
+	_ "image/jpeg"
+	_ "image/png"
+
+	"github.com/BurntSushi/xgbutil"
+	"github.com/BurntSushi/xgbutil/xgraphics"
+	xdraw "golang.org/x/image/draw"
+
+	"github.com/moolen/gllock/xw"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// backgroundScreenshot renders the captured screen
+	backgroundScreenshot = "screenshot"
+	// backgroundWallpaper renders the wallpaper of the root window
+	backgroundWallpaper = "wallpaper"
+	// backgroundPixelate renders the captured screen in large blocks
+	backgroundPixelate = "pixelate"
+	// backgroundBlur renders the captured screen blurred
+	backgroundBlur  = "blur"
+	backgroundColor = "color"
+	backgroundImage = "image"
+)
+
+// minPixelSize and minBlurRadius keep obfuscated screenshots unreadable,
+// smaller values are raised to them
+const (
+	minPixelSize  = 16
+	minBlurRadius = 12
+)
+
+// background is what the effects are applied to
+type background struct {
+	kind  string
+	color color.RGBA
+	path  string
+	// size is the block size or blur radius in pixels
+	size int
+}
+
+// parseBackground parses the -background flag: screenshot, wallpaper,
+// pixelate[:size], blur[:radius], a colour like #1d1f21 or a path to an image
+func parseBackground(spec string) (background, error) {
+	switch {
+	case spec == backgroundScreenshot || spec == backgroundWallpaper:
+		return background{kind: spec}, nil
+	case strings.HasPrefix(spec, "#"):
+		c, err := parseColor(spec)
+		return background{kind: backgroundColor, color: c}, err
+	case spec == backgroundPixelate || strings.HasPrefix(spec, backgroundPixelate+":"):
+		size, err := obfuscationSize(spec, minPixelSize)
+		return background{kind: backgroundPixelate, size: size}, err
+	case spec == backgroundBlur || strings.HasPrefix(spec, backgroundBlur+":"):
+		size, err := obfuscationSize(spec, minBlurRadius)
+		return background{kind: backgroundBlur, size: size}, err
+	}
+	if _, err := os.Stat(spec); err != nil {
+		return background{}, fmt.Errorf("invalid background: %s", err)
+	}
+	return background{kind: backgroundImage, path: spec}, nil
+}
+
+// obfuscationSize parses the size after the colon, it is never below min
+func obfuscationSize(spec string, min int) (int, error) {
+	i := strings.IndexByte(spec, ':')
+	if i < 0 {
+		return min, nil
+	}
+	size, err := strconv.Atoi(spec[i+1:])
+	if err != nil {
+		return 0, fmt.Errorf("invalid size in %s: %s", spec, err)
+	}
+	if size < min {
+		log.Warnf("%s is below the minimum, using %d", spec, min)
+		return min, nil
+	}
+	return size, nil
+}
+
+// parseColor parses #rgb and #rrggbb
+func parseColor(spec string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(spec, "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	var r, g, b uint8
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour: %s", spec)
+	}
+	if _, err := fmt.Sscanf(hex, "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour %s: %s", spec, err)
+	}
+	return color.RGBA{r, g, b, 0xff}, nil
+}
+
+// images returns one BGRX image per output.
+// The screen is only captured for screenshot, pixelate and blur,
+// matching windows are redacted before the screenshot is obfuscated.
+func (b background) images(x *xw.XW, outputs []xw.Output, redact *redactor) ([]*xgraphics.Image, error) {
+	var src image.Image
+	if b.kind == backgroundImage {
+		file, err := os.Open(b.path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		src, _, err = image.Decode(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %s", b.path, err)
+		}
+	}
+	images := make([]*xgraphics.Image, len(outputs))
+	for i, output := range outputs {
+		var img *xgraphics.Image
+		var err error
+		size := output.Rect.Size()
+		switch b.kind {
+		case backgroundColor:
+			img = xgraphics.New(x.Xu, image.Rect(0, 0, size.X, size.Y))
+			fill(img, img.Rect, b.color)
+		case backgroundImage:
+			img = toBGRX(x.Xu, cover(src, size))
+		case backgroundWallpaper:
+			img, err = x.Wallpaper(output.Rect)
+		default:
+			img, err = x.Capture(output.Rect)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("output %s: %s", output.Name, err)
+		}
+		images[i] = img
+	}
+	if !b.captures() {
+		return images, nil
+	}
+	if redact.enabled() {
+		clients, err := x.Clients()
+		if err != nil {
+			log.Warnf("failed to list windows, blanking the screenshot: %s", err)
+			redact.blank(images)
+		} else {
+			redact.redact(clients, outputs, images)
+		}
+	}
+	for _, img := range images {
+		switch b.kind {
+		case backgroundPixelate:
+			pixelate(img, img.Rect, b.size)
+		case backgroundBlur:
+			blur(img, img.Rect, b.size)
+		}
+	}
+	return images, nil
+}
+
+// captures reports whether the background shows the screen contents
+func (b background) captures() bool {
+	return b.kind == backgroundScreenshot || b.kind == backgroundPixelate || b.kind == backgroundBlur
+}
+
+// cover scales src to fill size, the overflow is cropped evenly
+func cover(src image.Image, size image.Point) *image.RGBA {
+	crop := src.Bounds()
+	if crop.Dx()*size.Y > crop.Dy()*size.X {
+		width := crop.Dy() * size.X / size.Y
+		crop.Min.X += (crop.Dx() - width) / 2
+		crop.Max.X = crop.Min.X + width
+	} else {
+		height := crop.Dx() * size.Y / size.X
+		crop.Min.Y += (crop.Dy() - height) / 2
+		crop.Max.Y = crop.Min.Y + height
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size.X, size.Y))
+	xdraw.ApproxBiLinear.Scale(dst, dst.Bounds(), src, crop, xdraw.Src, nil)
+	return dst
+}
+
+// toBGRX swaps the red and blue channel of rgba.
+// xu may be nil if the image is never sent to the X server.
+func toBGRX(xu *xgbutil.XUtil, rgba *image.RGBA) *xgraphics.Image {
+	// xgraphics.New opens a connection of its own if xu is nil
+	img := &xgraphics.Image{
+		X:      xu,
+		Pix:    make([]uint8, len(rgba.Pix)),
+		Stride: rgba.Stride,
+		Rect:   rgba.Rect,
+	}
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		img.Pix[i+0] = rgba.Pix[i+2]
+		img.Pix[i+1] = rgba.Pix[i+1]
+		img.Pix[i+2] = rgba.Pix[i+0]
+		img.Pix[i+3] = 0xff
+	}
+	return img
+}
+
+// fill paints r of img in c
+func fill(img *xgraphics.Image, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Rect)
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for i := img.PixOffset(r.Min.X, y); i < img.PixOffset(r.Max.X, y); i += 4 {
+			img.Pix[i+0] = c.B
+			img.Pix[i+1] = c.G
+			img.Pix[i+2] = c.R
+			img.Pix[i+3] = 0xff
+		}
+	}
+}
+
+// pixelate replaces every block of r with its average colour
+func pixelate(img *xgraphics.Image, r image.Rectangle, size int) {
+	r = r.Intersect(img.Rect)
+	for by := r.Min.Y; by < r.Max.Y; by += size {
+		for bx := r.Min.X; bx < r.Max.X; bx += size {
+			block := image.Rect(bx, by, bx+size, by+size).Intersect(r)
+			var sum [3]int
+			for y := block.Min.Y; y < block.Max.Y; y++ {
+				for i := img.PixOffset(block.Min.X, y); i < img.PixOffset(block.Max.X, y); i += 4 {
+					sum[0] += int(img.Pix[i+0])
+					sum[1] += int(img.Pix[i+1])
+					sum[2] += int(img.Pix[i+2])
+				}
+			}
+			n := block.Dx() * block.Dy()
+			fill(img, block, color.RGBA{uint8(sum[2] / n), uint8(sum[1] / n), uint8(sum[0] / n), 0xff})
+		}
+	}
+}
+
+// blur applies three box blurs of the given radius to r,
+// which is close to a gaussian blur. Pixels outside of r are not read.
+func blur(img *xgraphics.Image, r image.Rectangle, radius int) {
+	r = r.Intersect(img.Rect)
+	if r.Empty() || radius < 1 {
+		return
+	}
+	width, height := r.Dx(), r.Dy()
+	buf := make([]uint8, width*height*4)
+	tmp := make([]uint8, len(buf))
+	for y := 0; y < height; y++ {
+		copy(buf[y*width*4:(y+1)*width*4], img.Pix[img.PixOffset(r.Min.X, r.Min.Y+y):])
+	}
+	for i := 0; i < 3; i++ {
+		boxBlur(tmp, buf, height, width, 4, width*4, radius)
+		boxBlur(buf, tmp, width, height, width*4, 4, radius)
+	}
+	for y := 0; y < height; y++ {
+		copy(img.Pix[img.PixOffset(r.Min.X, r.Min.Y+y):], buf[y*width*4:(y+1)*width*4])
+	}
+}
+
+// boxBlur averages the colour channels along lines of length pixels,
+// step and lineStride are in bytes. Edge pixels are repeated.
+func boxBlur(dst, src []uint8, lines, length, step, lineStride, radius int) {
+	clamp := func(i int) int {
+		if i < 0 {
+			return 0
+		}
+		if i >= length {
+			return length - 1
+		}
+		return i
+	}
+	n := 2*radius + 1
+	for l := 0; l < lines; l++ {
+		base := l * lineStride
+		for c := 0; c < 3; c++ {
+			sum := 0
+			for i := -radius; i <= radius; i++ {
+				sum += int(src[base+clamp(i)*step+c])
+			}
+			for i := 0; i < length; i++ {
+				dst[base+i*step+c] = uint8(sum / n)
+				sum += int(src[base+clamp(i+radius+1)*step+c]) - int(src[base+clamp(i-radius)*step+c])
+			}
+		}
+		for i := 0; i < length; i++ {
+			dst[base+i*step+3] = 0xff
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestParseBackground(t *testing.T) {
+	file, err := ioutil.TempFile("", "gllock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+
+	for _, tc := range []struct {
+		spec string
+		want background
+		err  bool
+	}{
+		{spec: "screenshot", want: background{kind: backgroundScreenshot}},
+		{spec: "wallpaper", want: background{kind: backgroundWallpaper}},
+		{spec: "#1d1f21", want: background{kind: backgroundColor, color: color.RGBA{0x1d, 0x1f, 0x21, 0xff}}},
+		{spec: "#fff", want: background{kind: backgroundColor, color: color.RGBA{0xff, 0xff, 0xff, 0xff}}},
+		{spec: "#12345", err: true},
+		{spec: "#ggg", err: true},
+		{spec: "pixelate", want: background{kind: backgroundPixelate, size: minPixelSize}},
+		{spec: "pixelate:32", want: background{kind: backgroundPixelate, size: 32}},
+		// below the minimum the screenshot would stay readable
+		{spec: "pixelate:4", want: background{kind: backgroundPixelate, size: minPixelSize}},
+		{spec: "pixelate:-1", want: background{kind: backgroundPixelate, size: minPixelSize}},
+		{spec: "pixelate:big", err: true},
+		{spec: "blur", want: background{kind: backgroundBlur, size: minBlurRadius}},
+		{spec: "blur:1", want: background{kind: backgroundBlur, size: minBlurRadius}},
+		{spec: "blur:20", want: background{kind: backgroundBlur, size: 20}},
+		{spec: file.Name(), want: background{kind: backgroundImage, path: file.Name()}},
+		{spec: file.Name() + ".missing", err: true},
+	} {
+		got, err := parseBackground(tc.spec)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %+v", tc.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: got %+v, want %+v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestBoxBlur(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		line   []uint8
+		radius int
+		want   []uint8
+	}{
+		{"no radius", []uint8{30, 60, 90}, 0, []uint8{30, 60, 90}},
+		{"uniform", []uint8{50, 50, 50, 50}, 2, []uint8{50, 50, 50, 50}},
+		// pixels beyond the edge repeat the edge pixel
+		{"clamped at the edges", []uint8{30, 60, 90, 120}, 1, []uint8{40, 60, 90, 110}},
+		{"radius beyond the line", []uint8{0, 90}, 2, []uint8{36, 54}},
+	} {
+		// a single line of BGRX pixels with the value in every channel
+		src := make([]uint8, 4*len(tc.line))
+		for i, v := range tc.line {
+			src[4*i], src[4*i+1], src[4*i+2] = v, v, v
+		}
+		dst := make([]uint8, len(src))
+		boxBlur(dst, src, 1, len(tc.line), 4, len(src), tc.radius)
+		for i, want := range tc.want {
+			got := dst[4*i : 4*i+4]
+			if !reflect.DeepEqual(got, []uint8{want, want, want, 0xff}) {
+				t.Errorf("%s: pixel %d is %v, want %d", tc.name, i, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math/rand"
+	"time"
+
+	"github.com/go-gl/glfw/v3.1/glfw"
+)
+
+// clock is the time source of the effects
+type clock interface {
+	// elapsed returns the seconds since the start
+	elapsed() float64
+	// now returns the date passed to the effects
+	now() time.Time
+}
+
+// wallClock follows the real time, it is used on the lock screen
+type wallClock struct{}
+
+func (wallClock) elapsed() float64 {
+	return glfw.GetTime()
+}
+
+func (wallClock) now() time.Time {
+	return time.Now()
+}
+
+// stepClock advances by a fixed step per frame, independent of how long
+// a frame takes, so every run produces the same frames
+type stepClock struct {
+	// date is the date at frame 0
+	date  time.Time
+	step  float64
+	frame int
+}
+
+func (c *stepClock) elapsed() float64 {
+	return float64(c.frame) * c.step
+}
+
+func (c *stepClock) now() time.Time {
+	return c.date.Add(time.Duration(c.elapsed() * float64(time.Second)))
+}
+
+// advance moves to the next frame
+func (c *stepClock) advance() {
+	c.frame++
+}
+
+// maxSeed bounds the seeds. The seed uniform is the seed divided
+// by maxSeed, which float32 keeps exact for seeds below it.
+const maxSeed = 1 << 20
+
+// pickSeed returns seed, or a random seed if it is 0
+func pickSeed(seed int64) int64 {
+	if seed != 0 {
+		return seed
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(maxSeed-1) + 1
+}
+
+// seedUniform maps a seed in [1, maxSeed) to the seed uniform in (0, 1)
+func seedUniform(seed int64) float32 {
+	return float32(seed) / maxSeed
+}
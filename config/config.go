@@ -0,0 +1,286 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Config is the content of the gllock configuration file
+type Config struct {
+	// Hooks are commands that run at certain points of the lock life-cycle
+	Hooks Hooks `json:"hooks"`
+	// Lockout configures how failed attempts are throttled
+	Lockout Lockout `json:"lockout"`
+	// Audit configures where security events are recorded
+	Audit Audit `json:"audit"`
+	// Secrets configures which secrets outside the screen are cleared on lock
+	Secrets Secrets `json:"secrets"`
+	// Keybindings maps key combinations to commands that run while locked
+	Keybindings map[string]string `json:"keybindings"`
+	// Redact blanks sensitive windows in the screenshot
+	Redact Redact `json:"redact"`
+	// Pipelines are effects made of several passes, keyed by name.
+	// Output.Effect can refer to them.
+	Pipelines map[string][]Pass `json:"pipelines"`
+	// Outputs configures the lock screen per RandR output name.
+	// The section DefaultOutput applies to all outputs without a section.
+	Outputs map[string]Output `json:"outputs"`
+}
+
+// DefaultOutput is the name of the section used for unnamed outputs
+const DefaultOutput = "default"
+
+// Positions are the valid values of Output.Position and Output.TextPosition
+var Positions = []string{"center", "top", "bottom", "left", "right",
+	"top-left", "top-right", "bottom-left", "bottom-right"}
+
+// Output is what is shown on a single output
+type Output struct {
+	// Effect is a built-in effect like glitch, none or black,
+	// the name of an effect in the effect directories, a path
+	// or the name of a pipeline
+	Effect string `json:"effect"`
+	// Overlay is the path to an image shown on top of the effect
+	Overlay string `json:"overlay"`
+	// Position places the overlay: center, top, bottom, left, right,
+	// top-left, top-right, bottom-left or bottom-right
+	Position string `json:"position"`
+	// Text is shown below the overlay
+	Text string `json:"text"`
+	// TextPosition places the text, see Position
+	TextPosition string `json:"text-position"`
+	// Scale multiplies the size of overlay and text.
+	// Zero detects it from Xft.dpi or the physical size of the output.
+	Scale float64 `json:"scale"`
+	// Params override the parameters of the effect
+	Params map[string]ParamValue `json:"params"`
+}
+
+// ParamValue is the value of an effect parameter,
+// either a number or an array of numbers for vectors
+type ParamValue []float32
+
+// UnmarshalJSON accepts a number or an array of numbers
+func (v *ParamValue) UnmarshalJSON(b []byte) error {
+	var n float32
+	if err := json.Unmarshal(b, &n); err == nil {
+		*v = ParamValue{n}
+		return nil
+	}
+	var values []float32
+	if err := json.Unmarshal(b, &values); err != nil {
+		return fmt.Errorf("param must be a number or an array of numbers: %s", b)
+	}
+	*v = values
+	return nil
+}
+
+// Output returns the settings for the named output.
+// Fields that are not set fall back to the default section
+// and then to the glitch effect with a centered overlay.
+func (c *Config) Output(name string) Output {
+	def := c.Outputs[DefaultOutput]
+	if def.Effect == "" {
+		def.Effect = "glitch"
+	}
+	if def.Position == "" {
+		def.Position = "center"
+	}
+	if def.TextPosition == "" {
+		def.TextPosition = "bottom"
+	}
+	out, ok := c.Outputs[name]
+	if !ok {
+		return def
+	}
+	if out.Effect == "" {
+		out.Effect = def.Effect
+	}
+	if out.Overlay == "" {
+		out.Overlay = def.Overlay
+	}
+	if out.Position == "" {
+		out.Position = def.Position
+	}
+	if out.Text == "" {
+		out.Text = def.Text
+	}
+	if out.TextPosition == "" {
+		out.TextPosition = def.TextPosition
+	}
+	if out.Scale == 0 {
+		out.Scale = def.Scale
+	}
+	params := map[string]ParamValue{}
+	for name, v := range def.Params {
+		params[name] = v
+	}
+	for name, v := range out.Params {
+		params[name] = v
+	}
+	out.Params = params
+	return out
+}
+
+// Pass is a single step of a pipeline
+type Pass struct {
+	// Name is how later passes refer to the output of this pass
+	Name string `json:"name"`
+	// Effect is the shader of the pass, see Output.Effect
+	Effect string `json:"effect"`
+	// Inputs are bound to texture0, texture1 and so on: screenshot,
+	// previous (the output of the pass before), feedback (the last frame)
+	// or the name of an earlier pass. It defaults to previous.
+	Inputs []string `json:"inputs"`
+}
+
+// Hooks maps life-cycle events to shell commands
+type Hooks struct {
+	// Timeout is the maximum runtime of a single command
+	Timeout     Duration `json:"timeout"`
+	PreLock     []string `json:"pre-lock"`
+	PostGrab    []string `json:"post-grab"`
+	AuthFailure []string `json:"auth-failure"`
+	Lockout     []string `json:"lockout"`
+	PostUnlock  []string `json:"post-unlock"`
+}
+
+// Lockout ignores all input for Duration after Attempts failed attempts.
+// Input during a lockout does not count, the next lockout starts after
+// another Attempts failures. Zero Attempts disables the lockout.
+type Lockout struct {
+	Attempts int      `json:"attempts"`
+	Duration Duration `json:"duration"`
+}
+
+// Audit enables the sinks for security events
+type Audit struct {
+	// Journal sends events to journald
+	Journal bool `json:"journal"`
+	// Syslog sends events to a syslog daemon
+	Syslog *Syslog `json:"syslog"`
+	// File appends events as JSON lines to this path
+	File string `json:"file"`
+	// Webhook posts events to an HTTP endpoint
+	Webhook *Webhook `json:"webhook"`
+}
+
+// Syslog is the address of a syslog daemon.
+// An empty network uses the local syslog socket.
+type Syslog struct {
+	Network string `json:"network"`
+	Address string `json:"address"`
+}
+
+// Webhook is an HTTP endpoint that receives events as JSON
+type Webhook struct {
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Timeout Duration          `json:"timeout"`
+}
+
+// Secrets are opt-in steps that run when the screen is locked
+type Secrets struct {
+	// Selections clears the CLIPBOARD and PRIMARY selections
+	Selections bool `json:"selections"`
+	// SSHAgent removes all identities from the ssh-agent
+	SSHAgent bool `json:"ssh-agent"`
+	// SSHAgentSocket overrides $SSH_AUTH_SOCK
+	SSHAgentSocket string `json:"ssh-agent-socket"`
+	// GPGAgent makes the gpg-agent forget cached passphrases
+	GPGAgent bool `json:"gpg-agent"`
+	// GPGAgentSocket overrides the socket reported by gpgconf
+	GPGAgentSocket string `json:"gpg-agent-socket"`
+}
+
+// Redact selects the windows that are blanked before effects are applied
+type Redact struct {
+	// Classes are regular expressions matched against both parts of WM_CLASS
+	Classes []string `json:"classes"`
+	// Titles are regular expressions matched against the window title
+	Titles []string `json:"titles"`
+	// Fill is either blur or a colour like #000000, it defaults to blur
+	Fill string `json:"fill"`
+}
+
+// Duration is a time.Duration that is read from a string like "5s"
+type Duration time.Duration
+
+// UnmarshalJSON parses the duration using time.ParseDuration
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Default returns the configuration used when no file exists
+func Default() *Config {
+	return &Config{
+		Hooks: Hooks{
+			Timeout: Duration(10 * time.Second),
+		},
+		Lockout: Lockout{
+			Duration: Duration(30 * time.Second),
+		},
+	}
+}
+
+// DefaultPath returns the path of the config file in the XDG config directory
+func DefaultPath() string {
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	return filepath.Join(dir, "gllock", "config.json")
+}
+
+// Load reads the config file at path on top of the defaults.
+// A missing file is not an error.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err := json.NewDecoder(file).Decode(cfg); err != nil {
+		return nil, err
+	}
+	for name, out := range cfg.Outputs {
+		if err := checkPosition(out.Position); err != nil {
+			return nil, fmt.Errorf("output %s: position %s", name, err)
+		}
+		if err := checkPosition(out.TextPosition); err != nil {
+			return nil, fmt.Errorf("output %s: text-position %s", name, err)
+		}
+	}
+	return cfg, nil
+}
+
+// checkPosition accepts the Positions and empty strings,
+// which fall back to the default section
+func checkPosition(position string) error {
+	if position == "" {
+		return nil
+	}
+	for _, p := range Positions {
+		if position == p {
+			return nil
+		}
+	}
+	return fmt.Errorf("%q is not one of %s", position, strings.Join(Positions, ", "))
+}
@@ -0,0 +1,306 @@
+// Package effect finds and compiles the fragment shaders that are
+// applied to the screenshot of every output.
+//
+// An effect is a GLSL 4.1 fragment shader that is linked with fx.vert.
+// It receives TexCoord from the vertex shader and may declare these uniforms:
+//
+//	uniform sampler2D texture0; // the screenshot of the output, required
+//	uniform sampler2D texture1; // further inputs in a pipeline, up to texture7
+//	uniform float time;         // seconds since gllock started
+//	uniform ivec2 resolution;   // size of the output in pixels
+//	uniform vec2 mouse;         // pointer in pixels, relative to the bottom left
+//	uniform float seed;         // in [0, 1), varies the randomness between runs
+//	uniform int attempts;       // failed attempts so far
+//	uniform float sinceKey;     // seconds since the last key press, -1 if none
+//	uniform float sinceFailure; // seconds since the last failed attempt, -1 if none
+//	uniform float sinceUnlock;  // seconds since the password was accepted, -1 before
+//
+// Uniforms with an @param annotation are parameters of the effect,
+// all other uniforms are rejected because they would never be set:
+//
+//	uniform float radius; // @param default=12 min=1 max=64
+//
+// Shaders from shadertoy.com, with a mainImage function instead of main,
+// are wrapped automatically. They get iResolution, iTime, iMouse, iFrame,
+// iDate and the screenshot as iChannel0.
+package effect
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/gobuffalo/packr"
+
+	"github.com/moolen/gllock/gfx"
+)
+
+// Black leaves the output black, it has no shader
+const Black = "black"
+
+// Ext is the file extension of effects in the effect directories
+const Ext = ".frag"
+
+// Random picks one of the built-in effects on every lock
+const Random = "random"
+
+// rng picks the random effect
+var rng = rand.New(rand.NewSource(time.Now().UnixNano()))
+
+// Seed makes the choice of the random effect reproducible
+func Seed(seed int64) {
+	rng = rand.New(rand.NewSource(seed))
+}
+
+// builtins maps the names of the built-in effects to their shaders
+var builtins = map[string]string{
+	"glitch":    "fx.frag",
+	"none":      "regular.frag",
+	Black:       "",
+	"blur":      "blur.frag",
+	"pixelate":  "pixelate.frag",
+	"grayscale": "grayscale.frag",
+	"crt":       "crt.frag",
+	"vhs":       "vhs.frag",
+	"matrix":    "matrix.frag",
+	"melt":      "melt.frag",
+}
+
+// MaxInputs is the maximum number of textures of a pipeline pass
+const MaxInputs = 8
+
+// Uniforms is the uniform contract, it maps names to their GL types
+var Uniforms = map[string]uint32{
+	"texture0":     gl.SAMPLER_2D,
+	"time":         gl.FLOAT,
+	"resolution":   gl.INT_VEC2,
+	"mouse":        gl.FLOAT_VEC2,
+	"seed":         gl.FLOAT,
+	"attempts":     gl.INT,
+	"sinceKey":     gl.FLOAT,
+	"sinceFailure": gl.FLOAT,
+	"sinceUnlock":  gl.FLOAT,
+}
+
+func init() {
+	for i := 1; i < MaxInputs; i++ {
+		Uniforms[fmt.Sprintf("texture%d", i)] = gl.SAMPLER_2D
+	}
+}
+
+var box = packr.NewBox("../shaders")
+
+// Effect is a fragment shader
+type Effect struct {
+	Name string
+	// Path is the file the effect was read from, empty for built-in effects
+	Path string
+	// Source is the GLSL source, empty for the black effect
+	Source string
+	// Params are the annotated uniforms
+	Params []*gfx.Param
+	// Shadertoy is set for shaders with a mainImage function
+	Shadertoy bool
+}
+
+func newEffect(name, path, src string) (*Effect, error) {
+	params, err := gfx.ParseParams(src)
+	if err != nil {
+		return nil, fmt.Errorf("effect %s: %s", name, err)
+	}
+	return &Effect{Name: name, Path: path, Source: src, Params: params, Shadertoy: gfx.IsShadertoy(src)}, nil
+}
+
+// Dirs returns the directories that are searched for effects,
+// the user directory comes first:
+// $XDG_DATA_HOME/gllock/effects and $XDG_DATA_DIRS/gllock/effects
+func Dirs() []string {
+	home := os.Getenv("XDG_DATA_HOME")
+	if home == "" {
+		home = filepath.Join(os.Getenv("HOME"), ".local", "share")
+	}
+	dirs := []string{filepath.Join(home, "gllock", "effects")}
+	data := os.Getenv("XDG_DATA_DIRS")
+	if data == "" {
+		data = "/usr/local/share:/usr/share"
+	}
+	for _, dir := range filepath.SplitList(data) {
+		if dir != "" {
+			dirs = append(dirs, filepath.Join(dir, "gllock", "effects"))
+		}
+	}
+	return dirs
+}
+
+// isPath reports whether name refers to a file instead of an effect name
+func isPath(name string) bool {
+	return strings.ContainsRune(name, os.PathSeparator) || strings.HasSuffix(name, Ext)
+}
+
+// Load returns the effect with the given name or path.
+// Built-in effects take precedence over the effect directories,
+// so the names of the built-in effects always mean the same.
+func Load(name string) (*Effect, error) {
+	if isPath(name) {
+		return loadFile(strings.TrimSuffix(filepath.Base(name), Ext), name)
+	}
+	if name == Random {
+		name = pick()
+	}
+	if file, ok := builtins[name]; ok {
+		if file == "" {
+			return &Effect{Name: name}, nil
+		}
+		src, err := box.FindString(file)
+		if err != nil {
+			return nil, err
+		}
+		return newEffect(name, "", src)
+	}
+	for _, dir := range Dirs() {
+		path := filepath.Join(dir, name+Ext)
+		if _, err := os.Stat(path); err == nil {
+			return loadFile(name, path)
+		}
+	}
+	return nil, fmt.Errorf("unknown effect: %s", name)
+}
+
+func loadFile(name, path string) (*Effect, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newEffect(name, path, string(src))
+}
+
+// pick returns a random built-in effect that changes the screenshot
+func pick() string {
+	var names []string
+	for name := range builtins {
+		if name != "none" && name != Black {
+			names = append(names, name)
+		}
+	}
+	// sorted, so only the random number decides
+	sort.Strings(names)
+	return names[rng.Intn(len(names))]
+}
+
+// List returns all built-in effects followed by the effects found in Dirs.
+// Effects that are shadowed by an earlier one with the same name are left out.
+func List() ([]*Effect, error) {
+	var effects []*Effect
+	seen := map[string]bool{}
+	var names []string
+	for name := range builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		e, err := Load(name)
+		if err != nil {
+			return nil, err
+		}
+		effects = append(effects, e)
+		seen[name] = true
+	}
+	for _, dir := range Dirs() {
+		paths, err := filepath.Glob(filepath.Join(dir, "*"+Ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), Ext)
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			e, err := loadFile(name, path)
+			if err != nil {
+				return nil, err
+			}
+			effects = append(effects, e)
+		}
+	}
+	return effects, nil
+}
+
+// Program compiles the effect with fx.vert and checks its uniforms
+// against the contract. The black effect has no program and returns nil.
+func (e *Effect) Program() (*gfx.Program, error) {
+	if e.Source == "" {
+		return nil, nil
+	}
+	vertSrc, err := box.FindString("fx.vert")
+	if err != nil {
+		return nil, err
+	}
+	vert, err := gfx.NewShader(vertSrc, gl.VERTEX_SHADER)
+	if err != nil {
+		return nil, err
+	}
+	var frag *gfx.Shader
+	if e.Shadertoy {
+		frag, err = gfx.NewShadertoyShader(e.Source)
+	} else {
+		frag, err = gfx.NewShader(e.Source, gl.FRAGMENT_SHADER)
+	}
+	if err != nil {
+		vert.Delete()
+		return nil, fmt.Errorf("effect %s: %s", e, err)
+	}
+	prog, err := gfx.NewProgram(vert, frag)
+	if err != nil {
+		vert.Delete()
+		frag.Delete()
+		return nil, fmt.Errorf("effect %s: %s", e, err)
+	}
+	if err := e.check(prog.ActiveUniforms()); err != nil {
+		prog.Delete()
+		return nil, err
+	}
+	return prog, nil
+}
+
+// check verifies the active uniforms of the program
+func (e *Effect) check(uniforms map[string]uint32) error {
+	contract := Uniforms
+	if e.Shadertoy {
+		// many Shadertoy shaders do not read iChannel0
+		contract = gfx.ShadertoyUniforms
+	} else if _, ok := uniforms["texture0"]; !ok {
+		return fmt.Errorf("effect %s: texture0 is not used", e)
+	}
+	params := map[string]bool{}
+	for _, p := range e.Params {
+		params[p.Name] = true
+	}
+	for name, xtype := range uniforms {
+		if params[name] {
+			continue
+		}
+		want, ok := contract[name]
+		if !ok {
+			return fmt.Errorf("effect %s: unknown uniform %s", e, name)
+		}
+		if xtype != want {
+			return fmt.Errorf("effect %s: uniform %s has the wrong type", e, name)
+		}
+	}
+	return nil
+}
+
+// String returns the name and the path of effects read from a file
+func (e *Effect) String() string {
+	if e.Path == "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%s (%s)", e.Name, e.Path)
+}
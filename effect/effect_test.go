@@ -0,0 +1,207 @@
+package effect_test
+
+import (
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/moolen/gllock/effect"
+	"github.com/moolen/gllock/gfx"
+	"github.com/moolen/gllock/gfx/gltest"
+)
+
+func TestMain(m *testing.M) {
+	gltest.Main(m)
+}
+
+const width, height = 128, 96
+
+// renderTime and renderSeed are the time and seed the goldens are rendered with
+const (
+	renderTime = 1.5
+	renderSeed = 0.25
+)
+
+// builtins returns the built-in effects that have a shader
+func builtins(t *testing.T) []*effect.Effect {
+	all, err := effect.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var effects []*effect.Effect
+	for _, e := range all {
+		if e.Path == "" && e.Source != "" {
+			effects = append(effects, e)
+		}
+	}
+	return effects
+}
+
+// tempEffect writes src to an effect file and returns its path
+func tempEffect(t *testing.T, name, src string) string {
+	dir, err := ioutil.TempDir("", "gllock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name+effect.Ext)
+	if err := ioutil.WriteFile(path, []byte(src), 0644); err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestBuiltins(t *testing.T) {
+	found := map[string]bool{}
+	for _, e := range builtins(t) {
+		found[e.Name] = true
+		if e.Shadertoy {
+			t.Errorf("%s is detected as a Shadertoy shader", e)
+		}
+	}
+	for _, name := range []string{"glitch", "none", "blur", "pixelate", "grayscale", "crt", "vhs", "matrix", "melt"} {
+		if !found[name] {
+			t.Errorf("built-in effect %s is missing", name)
+		}
+	}
+	if e, err := effect.Load(effect.Black); err != nil || e.Source != "" {
+		t.Errorf("black effect is %v, %v", e, err)
+	}
+	for i := 0; i < 10; i++ {
+		e, err := effect.Load(effect.Random)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if e.Name == "none" || e.Name == effect.Black || e.Name == effect.Random {
+			t.Errorf("random picked %s", e.Name)
+		}
+	}
+	if _, err := effect.Load("does-not-exist"); err == nil {
+		t.Error("loading an unknown effect did not fail")
+	}
+}
+
+func TestSeed(t *testing.T) {
+	var picks [2][]string
+	for i := range picks {
+		effect.Seed(42)
+		for j := 0; j < 5; j++ {
+			e, err := effect.Load(effect.Random)
+			if err != nil {
+				t.Fatal(err)
+			}
+			picks[i] = append(picks[i], e.Name)
+		}
+	}
+	for j := range picks[0] {
+		if picks[0][j] != picks[1][j] {
+			t.Fatalf("the same seed picked %v and %v", picks[0], picks[1])
+		}
+	}
+}
+
+func TestLoadFile(t *testing.T) {
+	path := tempEffect(t, "toy", "void mainImage(out vec4 fragColor, in vec2 fragCoord) { fragColor = vec4(1.0); }\n")
+	defer os.RemoveAll(filepath.Dir(path))
+	e, err := effect.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Name != "toy" || e.Path != path || !e.Shadertoy {
+		t.Errorf("loaded %+v", e)
+	}
+}
+
+func TestContract(t *testing.T) {
+	for name, src := range map[string]string{
+		"unknown uniform": `#version 410 core
+in vec2 TexCoord;
+out vec4 color;
+uniform sampler2D texture0;
+uniform float brightness;
+void main() { color = texture(texture0, TexCoord) * brightness; }
+`,
+		"wrong type": `#version 410 core
+in vec2 TexCoord;
+out vec4 color;
+uniform sampler2D texture0;
+uniform int time;
+void main() { color = texture(texture0, TexCoord) * float(time); }
+`,
+		"no texture0": `#version 410 core
+in vec2 TexCoord;
+out vec4 color;
+void main() { color = vec4(TexCoord, 0.0, 1.0); }
+`,
+	} {
+		path := tempEffect(t, "contract", src)
+		defer os.RemoveAll(filepath.Dir(path))
+		e, err := effect.Load(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		gltest.Do(t, func() {
+			prog, err := e.Program()
+			if err == nil {
+				prog.Delete()
+				t.Errorf("%s: the effect was accepted", name)
+			}
+		})
+	}
+}
+
+// TestGolden renders every built-in effect at renderTime and renderSeed with
+// the default parameters and compares it with testdata/golden
+func TestGolden(t *testing.T) {
+	for _, e := range builtins(t) {
+		e := e
+		t.Run(e.Name, func(t *testing.T) {
+			var img *image.RGBA
+			var err error
+			gltest.Do(t, func() {
+				img, err = render(e)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			gltest.Golden(t, filepath.Join("testdata", "golden", e.Name+".png"), img)
+		})
+	}
+}
+
+// render draws a single frame of e on the test pattern,
+// like the lock screen does without any key presses
+func render(e *effect.Effect) (*image.RGBA, error) {
+	prog, err := e.Program()
+	if err != nil {
+		return nil, err
+	}
+	defer prog.Delete()
+	screenshot, err := gfx.NewTexture(gltest.Pattern(width, height), gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+	if err != nil {
+		return nil, err
+	}
+	p, err := gfx.NewPipeline([]*gfx.Pass{{Program: prog, Inputs: []string{gfx.InputScreenshot}}}, screenshot, width, height)
+	if err != nil {
+		return nil, err
+	}
+	defer p.Destroy()
+	p.Output = gfx.MustFramebuffer(width, height)
+	defer p.Output.Destroy()
+	p.Run(image.Rect(0, 0, width, height), func(prog *gfx.Program) {
+		prog.SetParams(nil)
+		gl.Uniform1f(prog.GetUniformLocation("time"), renderTime)
+		gl.Uniform2i(prog.GetUniformLocation("resolution"), width, height)
+		gl.Uniform2f(prog.GetUniformLocation("mouse"), width/2, height/2)
+		gl.Uniform1f(prog.GetUniformLocation("seed"), renderSeed)
+		gl.Uniform1i(prog.GetUniformLocation("attempts"), 0)
+		for _, name := range []string{"sinceKey", "sinceFailure", "sinceUnlock"} {
+			gl.Uniform1f(prog.GetUniformLocation(name), -1)
+		}
+	})
+	return p.Output.Image(), nil
+}
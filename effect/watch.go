@@ -0,0 +1,71 @@
+package effect
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// settle is how long a file has to stay unchanged before it is reported,
+// editors often write a file in several steps
+const settle = 100 * time.Millisecond
+
+// Watch reports changes of the given files on the returned channel.
+// Only effect files can be watched, built-in effects have no path.
+// The parent directories are watched, because many editors replace
+// files instead of writing to them. Paths are reported as absolute paths.
+func Watch(paths []string) (<-chan string, error) {
+	if len(paths) == 0 {
+		log.Warnf("no effect is loaded from a file, built-in effects are not watched")
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	watched := map[string]bool{}
+	for _, path := range paths {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		watched[abs] = true
+		if err := watcher.Add(filepath.Dir(abs)); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		log.Debugf("watching effect %s", abs)
+	}
+	changes := make(chan string, len(paths))
+	go func() {
+		defer watcher.Close()
+		due := map[string]bool{}
+		var timer <-chan time.Time
+		for {
+			select {
+			case ev, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if !watched[ev.Name] || ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				due[ev.Name] = true
+				timer = time.After(settle)
+			case <-timer:
+				for path := range due {
+					changes <- path
+					delete(due, path)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warnf("failed to watch effects: %s", err)
+			}
+		}
+	}()
+	return changes, nil
+}
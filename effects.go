@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/effect"
+	"github.com/moolen/gllock/gfx"
+)
+
+// listEffects prints the built-in effects and those found in the effect directories
+// together with their parameters
+func listEffects() error {
+	effects, err := effect.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range effects {
+		source := "built-in"
+		if e.Path != "" {
+			source = e.Path
+		}
+		fmt.Printf("%-16s %s\n", e.Name, source)
+		for _, p := range e.Params {
+			fmt.Printf("  %-14s %-5s default %s, range %s\n", p.Name, p.Type, formatValues(p.Default), p.Range())
+		}
+	}
+	return nil
+}
+
+// formatValues joins the components of a parameter value with commas
+func formatValues(values []float32) string {
+	var parts []string
+	for _, v := range values {
+		parts = append(parts, strconv.FormatFloat(float64(v), 'g', -1, 32))
+	}
+	return strings.Join(parts, ",")
+}
+
+// paramFlag collects repeated -param name=value flags
+type paramFlag map[string]config.ParamValue
+
+func (p paramFlag) String() string {
+	var parts []string
+	for name, values := range p {
+		parts = append(parts, name+"="+formatValues(values))
+	}
+	return strings.Join(parts, " ")
+}
+
+// Set parses name=value, vectors are written as 0.1,0.2
+func (p paramFlag) Set(s string) error {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 {
+		return fmt.Errorf("expected name=value: %s", s)
+	}
+	values, err := gfx.ParseParamValues(kv[1])
+	if err != nil {
+		return err
+	}
+	p[kv[0]] = values
+	return nil
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// frameWriter writes rendered frames to a file
+type frameWriter interface {
+	WriteFrame(img *image.RGBA) error
+	Close() error
+}
+
+// newFrameWriter picks the format by the extension of path:
+// .png writes a sequence of files, .gif an animation and .y4m a video
+func newFrameWriter(path string, size image.Point, fps int) (frameWriter, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".png":
+		return newPNGWriter(path), nil
+	case ".gif":
+		return &gifWriter{path: path, delay: (100 + fps/2) / fps}, nil
+	case ".y4m":
+		return newY4MWriter(path, size, fps)
+	}
+	return nil, fmt.Errorf("unsupported output %s, use .png, .gif or .y4m", path)
+}
+
+// pngWriter writes every frame to its own file
+type pngWriter struct {
+	// pattern has a verb for the frame number, e.g. demo-%04d.png
+	pattern string
+	index   int
+}
+
+// newPNGWriter numbers the files with the verb in path,
+// or appends -0000, -0001 and so on to the name
+func newPNGWriter(path string) *pngWriter {
+	if !strings.Contains(path, "%") {
+		ext := filepath.Ext(path)
+		path = strings.TrimSuffix(path, ext) + "-%04d" + ext
+	}
+	return &pngWriter{pattern: path}
+}
+
+func (w *pngWriter) WriteFrame(img *image.RGBA) error {
+	file, err := os.Create(fmt.Sprintf(w.pattern, w.index))
+	if err != nil {
+		return err
+	}
+	w.index++
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func (w *pngWriter) Close() error {
+	return nil
+}
+
+// gifWriter collects the frames and writes the animation on Close
+type gifWriter struct {
+	path string
+	// delay is the time per frame in 100ths of a second
+	delay int
+	anim  gif.GIF
+}
+
+func (w *gifWriter) WriteFrame(img *image.RGBA) error {
+	paletted := image.NewPaletted(img.Rect, palette.Plan9)
+	draw.FloydSteinberg.Draw(paletted, img.Rect, img, image.ZP)
+	w.anim.Image = append(w.anim.Image, paletted)
+	w.anim.Delay = append(w.anim.Delay, w.delay)
+	return nil
+}
+
+func (w *gifWriter) Close() error {
+	file, err := os.Create(w.path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(file, &w.anim); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// y4mWriter writes an uncompressed YUV4MPEG2 video with full chroma
+// resolution, which ffmpeg and mpv read directly
+type y4mWriter struct {
+	file *os.File
+	buf  *bufio.Writer
+}
+
+func newY4MWriter(path string, size image.Point, fps int) (*y4mWriter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &y4mWriter{file: file, buf: bufio.NewWriter(file)}
+	fmt.Fprintf(w.buf, "YUV4MPEG2 W%d H%d F%d:1 Ip A1:1 C444 XCOLORRANGE=FULL\n", size.X, size.Y, fps)
+	return w, nil
+}
+
+func (w *y4mWriter) WriteFrame(img *image.RGBA) error {
+	size := img.Rect.Size()
+	planes := make([]uint8, 3*size.X*size.Y)
+	n := size.X * size.Y
+	for y := 0; y < size.Y; y++ {
+		for x := 0; x < size.X; x++ {
+			c := img.RGBAAt(img.Rect.Min.X+x, img.Rect.Min.Y+y)
+			i := y*size.X + x
+			planes[i], planes[n+i], planes[2*n+i] = color.RGBToYCbCr(c.R, c.G, c.B)
+		}
+	}
+	w.buf.WriteString("FRAME\n")
+	_, err := w.buf.Write(planes)
+	return err
+}
+
+func (w *y4mWriter) Close() error {
+	if err := w.buf.Flush(); err != nil {
+		w.file.Close()
+		return err
+	}
+	return w.file.Close()
+}
@@ -1,6 +1,8 @@
 package gfx
 
 import (
+	"image"
+
 	"github.com/go-gl/gl/v4.1-core/gl"
 )
 
@@ -59,6 +61,21 @@ func NewFramebuffer(width, height int) (*Framebuffer, error) {
 	}, nil
 }
 
+// Resize reallocates the storage of the render texture and depth buffer.
+// The handles stay the same, so meshes referencing the texture stay valid.
+func (f *Framebuffer) Resize(width, height int) {
+	gl.BindTexture(gl.TEXTURE_2D, f.RenderTexture)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, gl.RGB, int32(width), int32(height), 0, gl.RGB, gl.UNSIGNED_BYTE, nil)
+	gl.BindTexture(gl.TEXTURE_2D, 0)
+
+	gl.BindRenderbuffer(gl.RENDERBUFFER, f.DepthBuffer)
+	gl.RenderbufferStorage(gl.RENDERBUFFER, gl.DEPTH_COMPONENT, int32(width), int32(height))
+	gl.BindRenderbuffer(gl.RENDERBUFFER, 0)
+
+	f.Texture.Width = int32(width)
+	f.Texture.Height = int32(height)
+}
+
 func (f *Framebuffer) Bind() {
 	gl.BindFramebuffer(gl.FRAMEBUFFER, f.Handle)
 
@@ -69,8 +86,30 @@ func (f *Framebuffer) Unbind() {
 
 }
 
-// Destroy -
+// Image reads the render texture back. GL stores the bottom row first,
+// the rows are flipped so the image is upright.
+func (f *Framebuffer) Image() *image.RGBA {
+	width, height := int(f.Texture.Width), int(f.Texture.Height)
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, f.Handle)
+	gl.PixelStorei(gl.PACK_ALIGNMENT, 1)
+	gl.ReadPixels(0, 0, int32(width), int32(height), gl.RGBA, gl.UNSIGNED_BYTE, gl.Ptr(img.Pix))
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, 0)
+
+	row := make([]uint8, img.Stride)
+	for y := 0; y < height/2; y++ {
+		top := img.Pix[y*img.Stride : (y+1)*img.Stride]
+		bottom := img.Pix[(height-1-y)*img.Stride : (height-y)*img.Stride]
+		copy(row, top)
+		copy(top, bottom)
+		copy(bottom, row)
+	}
+	return img
+}
+
+// Destroy deletes the framebuffer with its render texture and depth buffer
 func (f *Framebuffer) Destroy() {
 	gl.DeleteFramebuffers(1, &f.Handle)
-
+	gl.DeleteTextures(1, &f.RenderTexture)
+	gl.DeleteRenderbuffers(1, &f.DepthBuffer)
 }
@@ -0,0 +1,227 @@
+package gfx_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/moolen/gllock/gfx"
+	"github.com/moolen/gllock/gfx/gltest"
+	"github.com/moolen/gllock/gfx/gvd"
+)
+
+func TestMain(m *testing.M) {
+	gltest.Main(m)
+}
+
+const vert = `#version 410 core
+
+layout (location = 0) in vec3 position;
+layout (location = 1) in vec3 normal;
+layout (location = 2) in vec2 texCoord;
+
+out vec2 TexCoord;
+
+void main()
+{
+    gl_Position = vec4(position, 1.0);
+    TexCoord = texCoord;
+}
+`
+
+const copyFrag = `#version 410 core
+
+in vec2 TexCoord;
+out vec4 color;
+
+uniform sampler2D texture0;
+
+void main()
+{
+    color = texture(texture0, TexCoord);
+}
+`
+
+// invertFrag inverts texture0
+const invertFrag = `#version 410 core
+
+in vec2 TexCoord;
+out vec4 color;
+
+uniform sampler2D texture0;
+
+void main()
+{
+    color = vec4(1.0 - texture(texture0, TexCoord).rgb, 1.0);
+}
+`
+
+// mixFrag blends texture0 with texture1, e.g. the last frame
+const mixFrag = `#version 410 core
+
+in vec2 TexCoord;
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform sampler2D texture1;
+uniform float amount; // @param default=0.5 min=0 max=1
+
+void main()
+{
+    color = mix(texture(texture0, TexCoord), texture(texture1, TexCoord), amount);
+}
+`
+
+const width, height = 64, 48
+
+func TestMeshTexture(t *testing.T) {
+	var img *image.RGBA
+	gltest.Do(t, func() {
+		tex := gfx.MustTexture(gltest.Pattern(width, height), gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+		fb := gfx.MustFramebuffer(width, height)
+		defer fb.Destroy()
+		prog := gfx.MustMakeProgram(vert, copyFrag)
+		defer prog.Delete()
+
+		fb.Bind()
+		gl.Viewport(0, 0, width, height)
+		prog.Use()
+		gfx.NewMesh(gvd.PlaneVertices, gvd.PlaneIndices, []*gfx.Texture{tex}).Draw(prog)
+		fb.Unbind()
+		img = fb.Image()
+	})
+	gltest.Golden(t, "testdata/golden/mesh_texture.png", img)
+}
+
+func TestFramebufferResize(t *testing.T) {
+	var img *image.RGBA
+	gltest.Do(t, func() {
+		fb := gfx.MustFramebuffer(4, 4)
+		defer fb.Destroy()
+		fb.Resize(8, 2)
+		fb.Bind()
+		gl.Viewport(0, 0, 8, 2)
+		gl.ClearColor(1, 0, 0, 1)
+		gl.Clear(gl.COLOR_BUFFER_BIT)
+		gl.ClearColor(0, 0, 0, 1)
+		fb.Unbind()
+		img = fb.Image()
+	})
+	if img.Bounds() != image.Rect(0, 0, 8, 2) {
+		t.Fatalf("resized framebuffer is %v", img.Bounds())
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			if c := img.RGBAAt(x, y); c != (color.RGBA{255, 0, 0, 255}) {
+				t.Fatalf("pixel %d,%d is %v after clearing to red", x, y, c)
+			}
+		}
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	var img *image.RGBA
+	gltest.Do(t, func() {
+		screenshot := gfx.MustTexture(gltest.Pattern(width, height), gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+		invert := gfx.MustMakeProgram(vert, invertFrag)
+		defer invert.Delete()
+		mix := gfx.MustMakeProgram(vert, mixFrag)
+		defer mix.Delete()
+		// the inverted screenshot is blended with the screenshot by name
+		p, err := gfx.NewPipeline([]*gfx.Pass{
+			{Name: "inverted", Program: invert, Inputs: []string{gfx.InputScreenshot}},
+			{Program: invert, Inputs: []string{gfx.InputPrevious}},
+			{Program: mix, Inputs: []string{"inverted", gfx.InputPrevious}},
+		}, screenshot, width, height)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer p.Destroy()
+		p.Output = gfx.MustFramebuffer(width, height)
+		defer p.Output.Destroy()
+		p.Run(image.Rect(0, 0, width, height), func(prog *gfx.Program) {
+			prog.SetParams(map[string][]float32{"amount": {0.25}})
+		})
+		img = p.Output.Image()
+	})
+	if img != nil {
+		gltest.Golden(t, "testdata/golden/pipeline.png", img)
+	}
+}
+
+func TestPipelineFeedback(t *testing.T) {
+	var img *image.RGBA
+	gltest.Do(t, func() {
+		screenshot := gfx.MustTexture(gltest.Pattern(width, height), gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+		mix := gfx.MustMakeProgram(vert, mixFrag)
+		defer mix.Delete()
+		p, err := gfx.NewPipeline([]*gfx.Pass{
+			{Program: mix, Inputs: []string{gfx.InputScreenshot, gfx.InputFeedback}},
+		}, screenshot, width, height)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer p.Destroy()
+		p.Output = gfx.MustFramebuffer(width, height)
+		defer p.Output.Destroy()
+		// the screenshot fades in from black over the frames
+		for i := 0; i < 3; i++ {
+			p.Run(image.Rect(0, 0, width, height), func(prog *gfx.Program) {
+				prog.SetParams(nil)
+			})
+		}
+		img = p.Output.Image()
+	})
+	if img != nil {
+		gltest.Golden(t, "testdata/golden/pipeline_feedback.png", img)
+	}
+}
+
+func TestNewPipelineErrors(t *testing.T) {
+	prog := &gfx.Program{}
+	for name, passes := range map[string][]*gfx.Pass{
+		"no passes":      nil,
+		"no program":     {{Inputs: []string{gfx.InputScreenshot}}},
+		"unknown input":  {{Program: prog, Inputs: []string{"later"}}, {Name: "later", Program: prog}},
+		"duplicate name": {{Name: "a", Program: prog}, {Name: "a", Program: prog}, {Program: prog}},
+	} {
+		// validation fails before any GL call
+		if _, err := gfx.NewPipeline(passes, nil, width, height); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestParseParams(t *testing.T) {
+	params, err := gfx.ParseParams(mixFrag + "uniform vec2 offset; // @param default=0.1,0.2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(params) != 2 {
+		t.Fatalf("found %d params instead of 2", len(params))
+	}
+	amount := params[0]
+	if amount.Name != "amount" || amount.Type != "float" || amount.Range() != "[0, 1]" {
+		t.Errorf("amount is %+v", amount)
+	}
+	if err := amount.Check([]float32{2}); err == nil {
+		t.Error("2 is out of range, but passed the check")
+	}
+	if offset := params[1]; len(offset.Default) != 2 || offset.Range() != "[-inf, inf]" {
+		t.Errorf("offset is %+v", offset)
+	}
+	for _, src := range []string{
+		"uniform mat4 m; // @param default=1",
+		"uniform float f; // @param min=0",
+		"uniform float f; // @param default=2 max=1",
+		"uniform float f; // @param default=1 step=2",
+	} {
+		if _, err := gfx.ParseParams(src); err == nil {
+			t.Errorf("%s: no error", src)
+		}
+	}
+}
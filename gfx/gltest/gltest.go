@@ -0,0 +1,194 @@
+// Package gltest runs the GL code of tests on a headless context
+// and compares rendered images against golden PNGs.
+//
+// GL calls must be made on the thread that owns the context, so TestMain
+// hands the main thread to Main and tests run their GL code with Do:
+//
+//	func TestMain(m *testing.M) {
+//		gltest.Main(m)
+//	}
+//
+// Tests fail if no context can be created, unless they run with -short
+// or GLLOCK_SKIP_GL=1, then they are skipped. Goldens are regenerated
+// with go test -update, on Mesa llvmpipe so they match across machines:
+//
+//	LIBGL_ALWAYS_SOFTWARE=1 go test ./... -update
+package gltest
+
+import (
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/moolen/gllock/gfx/headless"
+)
+
+// Update writes the rendered images as new goldens instead of comparing them
+var Update = flag.Bool("update", false, "regenerate the golden images")
+
+// Tolerance is the difference per channel that is accepted,
+// GL implementations are allowed to round differently
+const Tolerance = 3
+
+var (
+	calls = make(chan func())
+	// contextErr is set if no context could be created
+	contextErr error
+)
+
+// Main creates the context on the main thread and serves the GL calls
+// of the tests until they are done, then it exits with their result
+func Main(m *testing.M) {
+	runtime.LockOSThread()
+	flag.Parse()
+	ctx, err := headless.New()
+	contextErr = err
+	done := make(chan int)
+	go func() {
+		done <- m.Run()
+	}()
+	for {
+		select {
+		case fn := <-calls:
+			fn()
+		case code := <-done:
+			if ctx != nil {
+				ctx.Destroy()
+			}
+			os.Exit(code)
+		}
+	}
+}
+
+// Do runs fn on the GL thread and waits for it.
+// The test fails if there is no GL context, unless skipping is allowed.
+func Do(t *testing.T, fn func()) {
+	t.Helper()
+	if contextErr != nil {
+		if testing.Short() || os.Getenv("GLLOCK_SKIP_GL") == "1" {
+			t.Skipf("no GL context: %s", contextErr)
+		}
+		t.Fatalf("no GL context, run with -short or GLLOCK_SKIP_GL=1 to skip: %s", contextErr)
+	}
+	done := make(chan struct{})
+	calls <- func() {
+		defer close(done)
+		fn()
+	}
+	<-done
+}
+
+// Pattern returns a test image with gradients, hard edges and text-like
+// detail, so blurs, shifts and colour changes show up in the goldens
+func Pattern(width, height int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{uint8(255 * x / width), uint8(255 * y / height), 128, 255}
+			if (x/8+y/8)%2 == 0 && y < height/2 {
+				c = color.RGBA{255, 255, 255, 255}
+			}
+			if y > 3*height/4 && x%4 == 0 {
+				c = color.RGBA{0, 0, 0, 255}
+			}
+			img.SetRGBA(x, y, c)
+		}
+	}
+	return img
+}
+
+// Golden compares img with the PNG at path. With -update the golden is
+// written instead. On a mismatch img is saved next to the golden with
+// the suffix .actual.png for inspection.
+func Golden(t *testing.T, path string, img *image.RGBA) {
+	t.Helper()
+	if *Update {
+		if err := writePNG(path, img); err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("updated %s", path)
+		return
+	}
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden %s does not exist, create it with go test -update", path)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden, err := png.Decode(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("failed to decode %s: %s", path, err)
+	}
+	if err := compare(golden, img); err != nil {
+		actual := strings.TrimSuffix(path, filepath.Ext(path)) + ".actual.png"
+		if werr := writePNG(actual, img); werr != nil {
+			t.Errorf("failed to save %s: %s", actual, werr)
+		}
+		t.Fatalf("%s: %s, the rendered image is %s", path, err, actual)
+	}
+}
+
+// compare returns an error if a channel of any pixel differs by more than Tolerance
+func compare(golden image.Image, img *image.RGBA) error {
+	if golden.Bounds() != img.Bounds() {
+		return fmt.Errorf("size is %v instead of %v", img.Bounds(), golden.Bounds())
+	}
+	var pixels, max int
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			want := color.RGBAModel.Convert(golden.At(x, y)).(color.RGBA)
+			got := img.RGBAAt(x, y)
+			d := maxDiff(want, got)
+			if d > Tolerance {
+				pixels++
+			}
+			if d > max {
+				max = d
+			}
+		}
+	}
+	if pixels > 0 {
+		return fmt.Errorf("%d pixels differ by up to %d", pixels, max)
+	}
+	return nil
+}
+
+func maxDiff(a, b color.RGBA) int {
+	max := 0
+	for _, d := range []int{
+		int(a.R) - int(b.R), int(a.G) - int(b.G), int(a.B) - int(b.B), int(a.A) - int(b.A),
+	} {
+		if d < 0 {
+			d = -d
+		}
+		if d > max {
+			max = d
+		}
+	}
+	return max
+}
+
+func writePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,8 @@
+// Package headless creates an OpenGL 4.1 core context without a window
+// on the screen, for rendering into framebuffers.
+//
+// A surfaceless EGL context is tried first, it needs neither a display
+// server nor a GPU. If the EGL implementation does not support it,
+// an invisible GLFW window is used, which needs an X server,
+// e.g. xvfb-run with Mesa llvmpipe.
+package headless
@@ -0,0 +1,106 @@
+package headless
+
+/*
+#cgo LDFLAGS: -lEGL
+#include <stdlib.h>
+#include <EGL/egl.h>
+#include <EGL/eglext.h>
+
+#ifndef EGL_PLATFORM_SURFACELESS_MESA
+#define EGL_PLATFORM_SURFACELESS_MESA 0x31DD
+#endif
+
+// surfacelessDisplay returns the display of the Mesa surfaceless platform,
+// which renders without a window system
+static EGLDisplay surfacelessDisplay() {
+	PFNEGLGETPLATFORMDISPLAYEXTPROC getPlatformDisplay =
+		(PFNEGLGETPLATFORMDISPLAYEXTPROC) eglGetProcAddress("eglGetPlatformDisplayEXT");
+	if (getPlatformDisplay == NULL) {
+		return EGL_NO_DISPLAY;
+	}
+	return getPlatformDisplay(EGL_PLATFORM_SURFACELESS_MESA, EGL_DEFAULT_DISPLAY, NULL);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// eglContext renders without a window system
+type eglContext struct {
+	display C.EGLDisplay
+	context C.EGLContext
+}
+
+func newEGL() (*eglContext, error) {
+	display := C.surfacelessDisplay()
+	if display == 0 {
+		return nil, fmt.Errorf("EGL surfaceless platform is not available")
+	}
+	var major, minor C.EGLint
+	if C.eglInitialize(display, &major, &minor) == C.EGL_FALSE {
+		return nil, eglError("eglInitialize")
+	}
+	c := &eglContext{display: display}
+	if C.eglBindAPI(C.EGL_OPENGL_API) == C.EGL_FALSE {
+		c.Destroy()
+		return nil, eglError("eglBindAPI")
+	}
+	// EGL_SURFACE_TYPE defaults to EGL_WINDOW_BIT,
+	// the surfaceless platform only has pbuffer configs
+	configAttribs := []C.EGLint{
+		C.EGL_SURFACE_TYPE, C.EGL_PBUFFER_BIT,
+		C.EGL_RENDERABLE_TYPE, C.EGL_OPENGL_BIT,
+		C.EGL_NONE,
+	}
+	var config C.EGLConfig
+	var configs C.EGLint
+	if C.eglChooseConfig(display, &configAttribs[0], &config, 1, &configs) == C.EGL_FALSE || configs == 0 {
+		c.Destroy()
+		return nil, eglError("eglChooseConfig")
+	}
+	contextAttribs := []C.EGLint{
+		C.EGL_CONTEXT_MAJOR_VERSION, 4,
+		C.EGL_CONTEXT_MINOR_VERSION, 1,
+		C.EGL_CONTEXT_OPENGL_PROFILE_MASK, C.EGL_CONTEXT_OPENGL_CORE_PROFILE_BIT,
+		C.EGL_NONE,
+	}
+	c.context = C.eglCreateContext(display, config, nil, &contextAttribs[0])
+	if c.context == nil {
+		c.Destroy()
+		return nil, eglError("eglCreateContext")
+	}
+	// there is no default framebuffer, everything is drawn into framebuffers
+	if C.eglMakeCurrent(display, nil, nil, c.context) == C.EGL_FALSE {
+		c.Destroy()
+		return nil, eglError("eglMakeCurrent")
+	}
+	// gl.Init loads the functions with GLX, which may not know this context
+	if err := gl.InitWithProcAddrFunc(eglProcAddress); err != nil {
+		c.Destroy()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *eglContext) Destroy() {
+	C.eglMakeCurrent(c.display, nil, nil, nil)
+	if c.context != nil {
+		C.eglDestroyContext(c.display, c.context)
+	}
+	C.eglTerminate(c.display)
+}
+
+func eglProcAddress(name string) unsafe.Pointer {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return unsafe.Pointer(C.eglGetProcAddress(cname))
+}
+
+func eglError(call string) error {
+	return fmt.Errorf("%s failed with EGL error 0x%x", call, int(C.eglGetError()))
+}
@@ -0,0 +1,42 @@
+package headless
+
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/go-gl/glfw/v3.1/glfw"
+)
+
+// glfwContext belongs to an invisible window, which needs an X server
+type glfwContext struct {
+	window *glfw.Window
+}
+
+func newGLFW() (*glfwContext, error) {
+	if err := glfw.Init(); err != nil {
+		return nil, fmt.Errorf("failed to initialize glfw: %s", err)
+	}
+	glfw.WindowHint(glfw.Visible, glfw.False)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	// nothing is drawn into the window, only into framebuffers
+	window, err := glfw.CreateWindow(1, 1, "gllock", nil, nil)
+	if err != nil {
+		glfw.Terminate()
+		return nil, err
+	}
+	window.MakeContextCurrent()
+	if err := gl.Init(); err != nil {
+		window.Destroy()
+		glfw.Terminate()
+		return nil, err
+	}
+	return &glfwContext{window: window}, nil
+}
+
+func (c *glfwContext) Destroy() {
+	c.window.Destroy()
+	glfw.Terminate()
+}
@@ -0,0 +1,32 @@
+package headless
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Context is a GL context that is current on the thread that created it
+type Context struct {
+	backend interface{ Destroy() }
+}
+
+// New creates a context and makes it current.
+// The calling goroutine must be locked to its OS thread.
+func New() (*Context, error) {
+	egl, err := newEGL()
+	if err == nil {
+		return &Context{backend: egl}, nil
+	}
+	log.Debugf("no surfaceless EGL context, falling back to GLFW: %s", err)
+	window, glfwErr := newGLFW()
+	if glfwErr != nil {
+		return nil, fmt.Errorf("%s, %s", err, glfwErr)
+	}
+	return &Context{backend: window}, nil
+}
+
+// Destroy releases the context
+func (c *Context) Destroy() {
+	c.backend.Destroy()
+}
@@ -0,0 +1,138 @@
+package gfx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// paramPattern matches uniforms with an @param annotation:
+//
+//	uniform float strength; // @param default=0.3 min=0 max=1
+var paramPattern = regexp.MustCompile(`(?m)^\s*uniform\s+(\w+)\s+(\w+)\s*;\s*//\s*@param\b(.*)$`)
+
+// paramSizes are the supported types and their number of components
+var paramSizes = map[string]int{
+	"float": 1,
+	"int":   1,
+	"vec2":  2,
+	"vec3":  3,
+	"vec4":  4,
+}
+
+// Param is a tunable uniform of a shader
+type Param struct {
+	Name string
+	// Type is the GLSL type: float, int, vec2, vec3 or vec4
+	Type    string
+	Default []float32
+	// Min and Max limit every component, nil if unbounded
+	Min *float32
+	Max *float32
+	// Location is set by the program, -1 if the uniform is unused
+	Location int32
+}
+
+// ParseParams returns the annotated uniforms of a shader source
+func ParseParams(src string) ([]*Param, error) {
+	var params []*Param
+	for _, m := range paramPattern.FindAllStringSubmatch(src, -1) {
+		p := &Param{Type: m[1], Name: m[2], Location: -1}
+		size, ok := paramSizes[p.Type]
+		if !ok {
+			return nil, fmt.Errorf("param %s: unsupported type %s", p.Name, p.Type)
+		}
+		for _, option := range strings.Fields(m[3]) {
+			kv := strings.SplitN(option, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("param %s: invalid option %s", p.Name, option)
+			}
+			values, err := ParseParamValues(kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("param %s: %s", p.Name, err)
+			}
+			switch kv[0] {
+			case "default":
+				p.Default = values
+			case "min", "max":
+				if len(values) != 1 {
+					return nil, fmt.Errorf("param %s: %s must be a single number", p.Name, kv[0])
+				}
+				if kv[0] == "min" {
+					p.Min = &values[0]
+				} else {
+					p.Max = &values[0]
+				}
+			default:
+				return nil, fmt.Errorf("param %s: unknown option %s", p.Name, kv[0])
+			}
+		}
+		if len(p.Default) != size {
+			return nil, fmt.Errorf("param %s: default needs %d components", p.Name, size)
+		}
+		if err := p.Check(p.Default); err != nil {
+			return nil, err
+		}
+		params = append(params, p)
+	}
+	return params, nil
+}
+
+// ParseParamValues parses a comma separated list of numbers, e.g. 0.1,0.2
+func ParseParamValues(s string) ([]float32, error) {
+	var values []float32
+	for _, field := range strings.Split(s, ",") {
+		v, err := strconv.ParseFloat(strings.TrimSpace(field), 32)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, float32(v))
+	}
+	return values, nil
+}
+
+// Check verifies the number of components and the range of values
+func (p *Param) Check(values []float32) error {
+	if len(values) != paramSizes[p.Type] {
+		return fmt.Errorf("param %s: %s needs %d components", p.Name, p.Type, paramSizes[p.Type])
+	}
+	for _, v := range values {
+		if p.Min != nil && v < *p.Min || p.Max != nil && v > *p.Max {
+			return fmt.Errorf("param %s: %v is out of range %s", p.Name, v, p.Range())
+		}
+	}
+	return nil
+}
+
+// Range describes the limits, e.g. [0, 1]
+func (p *Param) Range() string {
+	format := func(v *float32, unbounded string) string {
+		if v == nil {
+			return unbounded
+		}
+		return strconv.FormatFloat(float64(*v), 'g', -1, 32)
+	}
+	return fmt.Sprintf("[%s, %s]", format(p.Min, "-inf"), format(p.Max, "inf"))
+}
+
+// set uploads values to the uniform of prog
+func (p *Param) set(prog *Program, values []float32) {
+	if p.Location < 0 {
+		return
+	}
+	switch p.Type {
+	case "int":
+		gl.ProgramUniform1i(prog.Handle, p.Location, int32(values[0]))
+	case "float":
+		gl.ProgramUniform1fv(prog.Handle, p.Location, 1, &values[0])
+	case "vec2":
+		gl.ProgramUniform2fv(prog.Handle, p.Location, 1, &values[0])
+	case "vec3":
+		gl.ProgramUniform3fv(prog.Handle, p.Location, 1, &values[0])
+	case "vec4":
+		gl.ProgramUniform4fv(prog.Handle, p.Location, 1, &values[0])
+	}
+}
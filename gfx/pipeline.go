@@ -0,0 +1,216 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/moolen/gllock/gfx/gvd"
+)
+
+// Inputs of a pass besides the names of earlier passes
+const (
+	// InputScreenshot is the screenshot of the output
+	InputScreenshot = "screenshot"
+	// InputPrevious is the output of the pass before, or the screenshot for the first pass
+	InputPrevious = "previous"
+	// InputFeedback is the final image of the last frame, black in the first frame
+	InputFeedback = "feedback"
+)
+
+// Pass is a single draw call of a Pipeline
+type Pass struct {
+	// Name is how later passes refer to the output of this pass, it may be empty
+	Name    string
+	Program *Program
+	// Inputs are bound to texture0, texture1 and so on
+	Inputs []string
+}
+
+// Pipeline draws a list of passes, the last pass renders to the screen.
+// Framebuffers are allocated once: passes that are only read by the next
+// pass share two ping-pong framebuffers, passes that are read by name
+// get a framebuffer of their own.
+type Pipeline struct {
+	passes     []*Pass
+	screenshot *Texture
+	width      int
+	height     int
+	// targets are the framebuffers the passes render into,
+	// nil for the last pass
+	targets []*Framebuffer
+	// feedback[0] receives the current frame, feedback[1] holds the last one.
+	// Both are nil if no pass reads the last frame.
+	feedback [2]*Framebuffer
+	plane    *Mesh
+	// Output is the framebuffer the last pass renders into, nil for the window
+	Output *Framebuffer
+}
+
+// NewPipeline allocates the framebuffers for passes that render
+// an output of the given size. screenshot is the InputScreenshot texture.
+func NewPipeline(passes []*Pass, screenshot *Texture, width, height int) (*Pipeline, error) {
+	if len(passes) == 0 {
+		return nil, fmt.Errorf("pipeline without passes")
+	}
+	p := &Pipeline{
+		passes:     passes,
+		screenshot: screenshot,
+		width:      width,
+		height:     height,
+		targets:    make([]*Framebuffer, len(passes)),
+	}
+	// readByName are the passes that later passes read by name
+	readByName := map[string]bool{}
+	names := map[string]bool{}
+	feedback := false
+	for i, pass := range passes {
+		if pass.Program == nil {
+			return nil, fmt.Errorf("pass %d has no program", i)
+		}
+		for _, in := range pass.Inputs {
+			switch in {
+			case InputScreenshot, InputPrevious:
+			case InputFeedback:
+				feedback = true
+			default:
+				if !names[in] {
+					return nil, fmt.Errorf("pass %d reads %s, which is not an earlier pass", i, in)
+				}
+				readByName[in] = true
+			}
+		}
+		if pass.Name != "" {
+			if names[pass.Name] {
+				return nil, fmt.Errorf("duplicate pass name %s", pass.Name)
+			}
+			names[pass.Name] = true
+		}
+	}
+
+	p.plane = NewMesh(gvd.InvertedTexPlaneVertices, gvd.PlaneIndices, nil)
+	var pingPong [2]*Framebuffer
+	slot := 1
+	for i, pass := range passes[:len(passes)-1] {
+		if readByName[pass.Name] {
+			p.targets[i] = MustFramebuffer(width, height)
+			continue
+		}
+		slot = 1 - slot
+		if pingPong[slot] == nil {
+			pingPong[slot] = MustFramebuffer(width, height)
+		}
+		p.targets[i] = pingPong[slot]
+	}
+	if feedback {
+		for i := range p.feedback {
+			p.feedback[i] = MustFramebuffer(width, height)
+			p.feedback[i].Bind()
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+			p.feedback[i].Unbind()
+		}
+	}
+	return p, nil
+}
+
+// Run draws all passes, the last one into viewport of the window.
+// uniforms is called for every pass once its program is in use.
+func (p *Pipeline) Run(viewport image.Rectangle, uniforms func(*Program)) {
+	previous := p.screenshot
+	outputs := map[string]*Texture{}
+	for i, pass := range p.passes {
+		target := p.targets[i]
+		if i == len(p.passes)-1 {
+			target = p.feedback[0]
+		}
+		if target != nil {
+			target.Bind()
+			gl.Viewport(0, 0, int32(p.width), int32(p.height))
+			gl.Clear(gl.COLOR_BUFFER_BIT)
+		} else {
+			p.bindOutput()
+			gl.Viewport(int32(viewport.Min.X), int32(viewport.Min.Y), int32(viewport.Dx()), int32(viewport.Dy()))
+		}
+
+		textures := make([]*Texture, len(pass.Inputs))
+		for j, in := range pass.Inputs {
+			switch in {
+			case InputScreenshot:
+				textures[j] = p.screenshot
+			case InputPrevious:
+				textures[j] = previous
+			case InputFeedback:
+				textures[j] = p.feedback[1].Texture
+			default:
+				textures[j] = outputs[in]
+			}
+		}
+		p.plane.Textures = textures
+		pass.Program.Use()
+		uniforms(pass.Program)
+		p.plane.Draw(pass.Program)
+
+		if target != nil {
+			previous = target.Texture
+			if pass.Name != "" {
+				outputs[pass.Name] = target.Texture
+			}
+		}
+	}
+	p.bindOutput()
+	if p.feedback[0] == nil {
+		return
+	}
+	// show the frame and keep it for the next one
+	gl.BindFramebuffer(gl.READ_FRAMEBUFFER, p.feedback[0].Handle)
+	gl.BlitFramebuffer(0, 0, int32(p.width), int32(p.height),
+		int32(viewport.Min.X), int32(viewport.Min.Y), int32(viewport.Max.X), int32(viewport.Max.Y),
+		gl.COLOR_BUFFER_BIT, gl.NEAREST)
+	p.bindOutput()
+	p.feedback[0], p.feedback[1] = p.feedback[1], p.feedback[0]
+}
+
+// bindOutput binds the framebuffer of the final image
+func (p *Pipeline) bindOutput() {
+	if p.Output != nil {
+		p.Output.Bind()
+		return
+	}
+	gl.BindFramebuffer(gl.FRAMEBUFFER, 0)
+}
+
+// Passes returns the passes in the order they are drawn
+func (p *Pipeline) Passes() []*Pass {
+	return p.passes
+}
+
+// Resize adapts all framebuffers to a new output size
+func (p *Pipeline) Resize(width, height int) {
+	p.width, p.height = width, height
+	for _, fb := range p.framebuffers() {
+		fb.Resize(width, height)
+	}
+}
+
+// Destroy releases all framebuffers
+func (p *Pipeline) Destroy() {
+	for _, fb := range p.framebuffers() {
+		fb.Destroy()
+	}
+}
+
+// framebuffers returns every framebuffer once, ping-pong framebuffers
+// are shared by several passes
+func (p *Pipeline) framebuffers() []*Framebuffer {
+	seen := map[*Framebuffer]bool{}
+	var fbs []*Framebuffer
+	all := append([]*Framebuffer{}, p.targets...)
+	for _, fb := range append(all, p.feedback[:]...) {
+		if fb != nil && !seen[fb] {
+			seen[fb] = true
+			fbs = append(fbs, fb)
+		}
+	}
+	return fbs
+}
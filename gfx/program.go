@@ -1,6 +1,10 @@
 package gfx
 
-import "github.com/go-gl/gl/v4.1-core/gl"
+import (
+	"fmt"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
 
 // Program is a wrapper to the gl program
 type Program struct {
@@ -9,6 +13,9 @@ type Program struct {
 	// Shaders are a reference to a shader object,
 	// used only for proper clean-up of resources.
 	Shaders []*Shader
+	// Params are the annotated uniforms of the shaders,
+	// except those the linker optimized out
+	Params []*Param
 }
 
 // MustMakeProgram instantiates a new shader program
@@ -39,6 +46,9 @@ func NewProgram(shaders ...*Shader) (*Program, error) {
 	if err := prog.Link(); err != nil {
 		return nil, err
 	}
+	if err := prog.discoverParams(); err != nil {
+		return nil, err
+	}
 
 	return prog, nil
 }
@@ -75,3 +85,77 @@ func (prog *Program) Link() error {
 func (prog *Program) GetUniformLocation(name string) int32 {
 	return gl.GetUniformLocation(prog.Handle, gl.Str(name+"\x00"))
 }
+
+// ActiveUniforms returns the GL types of all uniforms the linker kept,
+// e.g. gl.FLOAT or gl.SAMPLER_2D. Unused uniforms are optimized out.
+func (prog *Program) ActiveUniforms() map[string]uint32 {
+	var count, maxLength int32
+	gl.GetProgramiv(prog.Handle, gl.ACTIVE_UNIFORMS, &count)
+	gl.GetProgramiv(prog.Handle, gl.ACTIVE_UNIFORM_MAX_LENGTH, &maxLength)
+	uniforms := map[string]uint32{}
+	for i := int32(0); i < count; i++ {
+		var length, size int32
+		var xtype uint32
+		name := make([]uint8, maxLength+1)
+		gl.GetActiveUniform(prog.Handle, uint32(i), maxLength+1, &length, &size, &xtype, &name[0])
+		uniforms[string(name[:length])] = xtype
+	}
+	return uniforms
+}
+
+// paramTypes maps the GLSL types of parameters to GL types
+var paramTypes = map[string]uint32{
+	"float": gl.FLOAT,
+	"int":   gl.INT,
+	"vec2":  gl.FLOAT_VEC2,
+	"vec3":  gl.FLOAT_VEC3,
+	"vec4":  gl.FLOAT_VEC4,
+}
+
+// discoverParams finds the annotated uniforms through introspection
+// and sets them to their defaults
+func (prog *Program) discoverParams() error {
+	active := prog.ActiveUniforms()
+	for _, shader := range prog.Shaders {
+		params, err := ParseParams(shader.Source)
+		if err != nil {
+			return err
+		}
+		for _, p := range params {
+			xtype, ok := active[p.Name]
+			if !ok {
+				continue
+			}
+			if xtype != paramTypes[p.Type] {
+				return fmt.Errorf("param %s: type does not match the uniform", p.Name)
+			}
+			p.Location = prog.GetUniformLocation(p.Name)
+			p.set(prog, p.Default)
+			prog.Params = append(prog.Params, p)
+		}
+	}
+	return nil
+}
+
+// Param returns the parameter with the given name or nil
+func (prog *Program) Param(name string) *Param {
+	for _, p := range prog.Params {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// SetParams uploads values to the parameters of the program.
+// Parameters without a value are reset to their default,
+// values must have been checked with Param.Check.
+func (prog *Program) SetParams(values map[string][]float32) {
+	for _, p := range prog.Params {
+		v, ok := values[p.Name]
+		if !ok {
+			v = p.Default
+		}
+		p.set(prog, v)
+	}
+}
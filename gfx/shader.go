@@ -12,6 +12,8 @@ import (
 type Shader struct {
 	// Handle is the shader handle used for various gl_* calls
 	Handle uint32
+	// Source is the source code, it is searched for parameters
+	Source string
 }
 
 // NewShaderFromFile reads the shader source code
@@ -31,7 +33,7 @@ func NewShaderFromFile(file string, sType uint32) (*Shader, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Shader{Handle: handle}, nil
+	return &Shader{Handle: handle, Source: string(src)}, nil
 }
 
 // NewShader compiles the given source code
@@ -46,7 +48,7 @@ func NewShader(src string, sType uint32) (*Shader, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &Shader{Handle: handle}, nil
+	return &Shader{Handle: handle, Source: src}, nil
 }
 
 // Delete deletes the shader
@@ -0,0 +1,105 @@
+package gfx
+
+import (
+	"fmt"
+	"image"
+	"regexp"
+	"time"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+)
+
+// shadertoyHeader declares the inputs of a Shadertoy shader.
+// #line keeps the line numbers of compile errors in sync with the file.
+const shadertoyHeader = `#version 410 core
+
+in vec2 TexCoord;
+out vec4 shadertoyColor;
+
+uniform vec3 iResolution;
+uniform float iTime;
+uniform vec4 iMouse;
+uniform int iFrame;
+uniform vec4 iDate;
+uniform sampler2D iChannel0;
+uniform sampler2D iChannel1;
+uniform sampler2D iChannel2;
+uniform sampler2D iChannel3;
+
+#line 1
+`
+
+// shadertoyFooter calls mainImage with the pixel inside of the output.
+// gl_FragCoord can not be used, it is relative to the window
+// that spans all outputs.
+const shadertoyFooter = `
+void main()
+{
+    mainImage(shadertoyColor, TexCoord * iResolution.xy);
+    shadertoyColor.a = 1.0;
+}
+`
+
+var (
+	mainImagePattern = regexp.MustCompile(`\bvoid\s+mainImage\s*\(`)
+	mainPattern      = regexp.MustCompile(`\bvoid\s+main\s*\(`)
+)
+
+// ShadertoyUniforms maps the Shadertoy uniforms to their GL types
+var ShadertoyUniforms = map[string]uint32{
+	"iResolution": gl.FLOAT_VEC3,
+	"iTime":       gl.FLOAT,
+	"iMouse":      gl.FLOAT_VEC4,
+	"iFrame":      gl.INT,
+	"iDate":       gl.FLOAT_VEC4,
+	"iChannel0":   gl.SAMPLER_2D,
+	"iChannel1":   gl.SAMPLER_2D,
+	"iChannel2":   gl.SAMPLER_2D,
+	"iChannel3":   gl.SAMPLER_2D,
+}
+
+// ShadertoyInput is the state that is passed to a Shadertoy shader every frame
+type ShadertoyInput struct {
+	// Time is the playback time in seconds
+	Time float64
+	// Frame is the number of the frame, starting at 0
+	Frame int
+	// Resolution is the size of the output in pixels
+	Resolution image.Point
+	// Mouse is the pointer in pixels, relative to the bottom left
+	Mouse image.Point
+	Date  time.Time
+}
+
+// IsShadertoy reports whether src has a mainImage function
+// instead of main, like the shaders on shadertoy.com
+func IsShadertoy(src string) bool {
+	return mainImagePattern.MatchString(src) && !mainPattern.MatchString(src)
+}
+
+// ShadertoySource wraps a Shadertoy shader, so it can be compiled as fragment shader
+func ShadertoySource(src string) string {
+	return shadertoyHeader + src + shadertoyFooter
+}
+
+// NewShadertoyShader compiles a Shadertoy shader
+func NewShadertoyShader(src string) (*Shader, error) {
+	return NewShader(ShadertoySource(src), gl.FRAGMENT_SHADER)
+}
+
+// SetShadertoyUniforms feeds in to the program, which must be in use.
+// iChannel0 to iChannel3 are the texture units 0 to 3.
+func SetShadertoyUniforms(prog *Program, in ShadertoyInput) {
+	gl.Uniform3f(prog.GetUniformLocation("iResolution"), float32(in.Resolution.X), float32(in.Resolution.Y), 1)
+	gl.Uniform1f(prog.GetUniformLocation("iTime"), float32(in.Time))
+	// no button is ever pressed, so there is no click position
+	gl.Uniform4f(prog.GetUniformLocation("iMouse"), float32(in.Mouse.X), float32(in.Mouse.Y), 0, 0)
+	gl.Uniform1i(prog.GetUniformLocation("iFrame"), int32(in.Frame))
+	midnight := time.Date(in.Date.Year(), in.Date.Month(), in.Date.Day(), 0, 0, 0, 0, in.Date.Location())
+	// months start at 0 on Shadertoy
+	gl.Uniform4f(prog.GetUniformLocation("iDate"), float32(in.Date.Year()), float32(in.Date.Month()-1),
+		float32(in.Date.Day()), float32(in.Date.Sub(midnight).Seconds()))
+	for i := 0; i < 4; i++ {
+		gl.Uniform1i(prog.GetUniformLocation(fmt.Sprintf("iChannel%d", i)), int32(i))
+	}
+}
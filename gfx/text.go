@@ -0,0 +1,46 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// textPadding is the space around the text in pixels
+const textPadding = 8
+
+// TextImage renders lines of text with a fixed-size bitmap font
+// on a solid background
+func TextImage(lines []string, fg, bg color.Color) *image.RGBA {
+	face := basicfont.Face7x13
+	lineHeight := face.Metrics().Height.Ceil()
+	var width int
+	for _, line := range lines {
+		if w := font.MeasureString(face, line).Ceil(); w > width {
+			width = w
+		}
+	}
+	img := image.NewRGBA(image.Rect(0, 0, width+2*textPadding, len(lines)*lineHeight+2*textPadding))
+	draw.Draw(img, img.Bounds(), image.NewUniform(bg), image.Point{}, draw.Src)
+	d := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(fg),
+		Face: face,
+	}
+	for i, line := range lines {
+		d.Dot = fixed.P(textPadding, textPadding+i*lineHeight+face.Metrics().Ascent.Ceil())
+		d.DrawString(line)
+	}
+	return img
+}
+
+// MustTextTexture renders the text into a texture.
+// This func panics on error
+func MustTextTexture(lines []string, fg, bg color.Color) *Texture {
+	return MustTexture(TextImage(lines, fg, bg), gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+}
@@ -37,11 +37,19 @@ func NewTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
 	if rgba.Stride != rgba.Rect.Size().X*4 {
 		return nil, errUnsupportedStride
 	}
-	width := int32(rgba.Rect.Size().X)
-	height := int32(rgba.Rect.Size().Y)
+	return NewTextureFromPixels(rgba.Pix, rgba.Rect.Size().X, rgba.Rect.Size().Y, gl.RGBA, wrapR, wrapS)
+}
+
+// NewTextureFromPixels uploads tightly packed 32-bit pixels without
+// converting them first. format is the order of the channels,
+// e.g. gl.RGBA or gl.BGRA for images captured from the X server.
+func NewTextureFromPixels(pix []uint8, width, height int, format uint32, wrapR, wrapS int32) (*Texture, error) {
+	if len(pix) != width*height*4 {
+		return nil, errUnsupportedStride
+	}
 	texture := Texture{
-		Width:  width,
-		Height: height,
+		Width:  int32(width),
+		Height: int32(height),
 	}
 	gl.GenTextures(1, &texture.Handle)
 
@@ -53,13 +61,19 @@ func NewTexture(img image.Image, wrapR, wrapS int32) (*Texture, error) {
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MIN_FILTER, gl.LINEAR)
 	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_MAG_FILTER, gl.LINEAR)
 
-	gl.TexImage2D(gl.TEXTURE_2D, 0, int32(gl.SRGB_ALPHA), texture.Width, texture.Height, 0, uint32(gl.RGBA), uint32(gl.UNSIGNED_BYTE), gl.Ptr(rgba.Pix))
-
-	gl.GenerateMipmap(texture.Handle)
+	gl.TexImage2D(gl.TEXTURE_2D, 0, int32(gl.SRGB_ALPHA), texture.Width, texture.Height, 0, format, uint32(gl.UNSIGNED_BYTE), gl.Ptr(pix))
 
 	return &texture, nil
 }
 
+// SetOpaque makes the texture ignore its alpha channel,
+// e.g. for BGRX images where the fourth byte is undefined
+func (tex *Texture) SetOpaque() {
+	tex.Bind(gl.TEXTURE0)
+	defer tex.Unbind()
+	gl.TexParameteri(gl.TEXTURE_2D, gl.TEXTURE_SWIZZLE_A, gl.ONE)
+}
+
 // Bind -
 func (tex *Texture) Bind(unit uint32) {
 	gl.ActiveTexture(unit)
@@ -0,0 +1,152 @@
+package hook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/moolen/gllock/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// Event is a point in the lock life-cycle at which hooks run
+type Event string
+
+const (
+	// PreLock runs before the screen is captured
+	PreLock Event = "pre-lock"
+	// PostGrab runs once keyboard and pointer are grabbed
+	PostGrab Event = "post-grab"
+	// AuthFailure runs after every wrong password
+	AuthFailure Event = "auth-failure"
+	// Lockout runs when too many attempts failed
+	Lockout Event = "lockout"
+	// PostUnlock runs after the lock screen has been closed
+	PostUnlock Event = "post-unlock"
+	// Keybinding runs when a whitelisted key is pressed while locked
+	Keybinding Event = "keybinding"
+)
+
+// Runner executes the shell commands registered for an event.
+// Every command is killed once Timeout is exceeded.
+type Runner struct {
+	Commands map[Event][]string
+	Timeout  time.Duration
+
+	wg sync.WaitGroup
+	// mu guards closed, so no command is added while Wait is waiting
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewRunner creates a Runner from the hooks section of the config file
+func NewRunner(cfg config.Hooks) *Runner {
+	return &Runner{
+		Timeout: time.Duration(cfg.Timeout),
+		Commands: map[Event][]string{
+			PreLock:     cfg.PreLock,
+			PostGrab:    cfg.PostGrab,
+			AuthFailure: cfg.AuthFailure,
+			Lockout:     cfg.Lockout,
+			PostUnlock:  cfg.PostUnlock,
+		},
+	}
+}
+
+// Fire runs all commands of the event in the background, unless the
+// Runner is closed. It never blocks, so it is safe to call from the
+// X event loop or the render loop.
+func (r *Runner) Fire(ev Event, env map[string]string) {
+	for _, cmd := range r.Commands[ev] {
+		r.Exec(ev, cmd, env)
+	}
+}
+
+// Exec runs a single command in the background on behalf of the event.
+// It does nothing once the Runner is closed.
+func (r *Runner) Exec(ev Event, command string, env map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		log.Debugf("hooks closed, not running %s hook: %s", ev, command)
+		return
+	}
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.exec(ev, command, env)
+	}()
+}
+
+// Run runs all commands of the event and waits until they exited
+func (r *Runner) Run(ev Event, env map[string]string) {
+	r.Fire(ev, env)
+	r.Wait()
+}
+
+// Wait blocks until all commands started by Fire have exited.
+// Call Close first if hooks may still be fired concurrently.
+func (r *Runner) Wait() {
+	r.wg.Wait()
+}
+
+// Close stops Fire and Exec from starting new commands.
+// Commands that already run are not affected.
+func (r *Runner) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+}
+
+func (r *Runner) exec(ev Event, command string, env map[string]string) {
+	ctx := context.Background()
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+	cmd := exec.Command("/bin/sh", "-c", command)
+	// the hook runs in its own process group, so a timeout also kills
+	// the jobs the shell started in the background
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Env = append(os.Environ(),
+		"GLLOCK_EVENT="+string(ev),
+		fmt.Sprintf("GLLOCK_PID=%d", os.Getpid()),
+		fmt.Sprintf("GLLOCK_TIME=%d", time.Now().Unix()),
+	)
+	for k, v := range env {
+		cmd.Env = append(cmd.Env, k+"="+v)
+	}
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	log.Debugf("running %s hook: %s", ev, command)
+	if err := cmd.Start(); err != nil {
+		log.Warnf("%s hook failed: %s: %s", ev, command, err)
+		return
+	}
+	exited := make(chan struct{})
+	go func() {
+		select {
+		case <-ctx.Done():
+			syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+		case <-exited:
+		}
+	}()
+	err := cmd.Wait()
+	close(exited)
+	if ctx.Err() == context.DeadlineExceeded {
+		log.Warnf("%s hook timed out after %s: %s", ev, r.Timeout, command)
+		return
+	}
+	if err != nil {
+		log.Warnf("%s hook failed: %s: %s: %s", ev, command, err, out.Bytes())
+		return
+	}
+	log.Debugf("%s hook finished: %s", ev, out.Bytes())
+}
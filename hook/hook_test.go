@@ -0,0 +1,83 @@
+package hook
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gllock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+
+	r := &Runner{Commands: map[Event][]string{
+		AuthFailure: {`echo "$GLLOCK_EVENT $GLLOCK_ATTEMPTS" > ` + out},
+	}}
+	r.Run(AuthFailure, map[string]string{"GLLOCK_ATTEMPTS": "3"})
+	data, err := ioutil.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(string(data)); got != "auth-failure 3" {
+		t.Errorf("hook wrote %q, want %q", got, "auth-failure 3")
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	r := &Runner{
+		Timeout: 200 * time.Millisecond,
+		Commands: map[Event][]string{
+			// the background job keeps the output pipe open,
+			// Run only returns once it has been killed as well
+			PreLock: {"sleep 60 & sleep 60"},
+		},
+	}
+	start := time.Now()
+	r.Run(PreLock, nil)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run returned after %s, the hook was not killed", elapsed)
+	}
+}
+
+func TestCloseWhileFiring(t *testing.T) {
+	r := &Runner{Commands: map[Event][]string{AuthFailure: {"true"}}}
+	stop := make(chan struct{})
+	fired := make(chan struct{})
+	go func() {
+		defer close(fired)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				r.Fire(AuthFailure, nil)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	r.Close()
+	r.Wait()
+	close(stop)
+	<-fired
+
+	// nothing runs after Close, so Wait returns immediately
+	r.Fire(AuthFailure, nil)
+	done := make(chan struct{})
+	go func() {
+		r.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Error("Wait blocks on a hook fired after Close")
+	}
+}
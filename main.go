@@ -4,19 +4,23 @@ import (
 	"flag"
 	"fmt"
 	"image"
+	"math"
 	"os"
 	"os/signal"
 	"runtime"
+	"strconv"
+	"sync"
+	"time"
 
-	"github.com/moolen/glitchlock/snap"
-
-	"github.com/gobuffalo/packr"
+	"github.com/BurntSushi/xgb/xproto"
 
 	"github.com/go-gl/gl/v4.1-core/gl"
 	"github.com/go-gl/glfw/v3.1/glfw"
 
-	"github.com/moolen/gllock/gfx"
-	"github.com/moolen/gllock/gfx/gvd"
+	"github.com/moolen/gllock/audit"
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/effect"
+	"github.com/moolen/gllock/hook"
 	"github.com/moolen/gllock/xw"
 	log "github.com/sirupsen/logrus"
 )
@@ -34,7 +38,20 @@ func main() {
 
 	flagVersion := flag.Bool("version", false, "show version and exit")
 	flagOverlay := flag.String("overlay", "", "specify a path to an image. it will be overlayed at the center of the screen. This image should be smaller than the screen dimensions.")
-	flagDebug := flag.Bool("debug", false, "debug mode logs additional information (for instance your password)")
+	flagDebug := flag.Bool("debug", false, "debug mode logs additional information")
+	flagConfig := flag.String("config", config.DefaultPath(), "path to the configuration file")
+	flagCursor := flag.String("cursor", xw.CursorHidden, "pointer shown while locked: hidden, visible, a PNG or Xcursor file or a themed cursor like Adwaita/left_ptr")
+	flagSummary := flag.String("summary", summaryStderr, "how failed attempts are reported after unlock: none, stderr, notify or frame")
+	flagEffect := flag.String("effect", "", "effect applied to all outputs: a built-in effect, the name of an effect in the effect directories or a path to a fragment shader. See gllock list-effects")
+	flagParams := paramFlag{}
+	flag.Var(flagParams, "param", "override an effect parameter on all outputs, e.g. strength=0.1. Can be repeated")
+	flagWatch := flag.Bool("watch", false, "recompile effect files when they change, for effect development. Built-in effects are not watched")
+	flagBackground := flag.String("background", backgroundScreenshot, "what the effect is applied to: screenshot, wallpaper, pixelate[:size], blur[:radius], a colour like #1d1f21 or a path to an image. Only screenshot, pixelate and blur capture the screen")
+	flagInput := flag.String("input", "", "render: image the effect is applied to")
+	flagOut := flag.String("out", "", "render: output file, .gif, .y4m or .png for a sequence like frame-%04d.png")
+	flagFrames := flag.Int("frames", 120, "render: number of frames")
+	flagFPS := flag.Int("fps", 30, "render: frames per second, the time step is 1/fps")
+	flagSeed := flag.Int64("seed", 0, "seed of the randomness of the effects and of -effect random, between 1 and 1048575. 0 picks a random seed. The seed is logged, so a run can be reproduced")
 	flag.Parse()
 
 	if *flagVersion {
@@ -42,176 +59,343 @@ func main() {
 		return
 	}
 
+	// flags may follow the command, e.g. gllock preview -effect crt
+	command := flag.Arg(0)
+	if command != "" {
+		flag.CommandLine.Parse(flag.Args()[1:])
+	}
+	switch command {
+	case "", commandPreview, commandRender:
+	case "list-effects":
+		if err := listEffects(); err != nil {
+			log.Fatal(err)
+		}
+		return
+	default:
+		log.Fatalf("unknown command: %s", command)
+	}
+
+	switch *flagSummary {
+	case summaryNone, summaryStderr, summaryNotify, summaryFrame:
+	default:
+		log.Fatalf("invalid summary mode: %s", *flagSummary)
+	}
+
 	if *flagDebug {
 		log.SetLevel(log.DebugLevel)
 		log.Debugln("enabled debug mode")
 	}
 
-	// capture screen before we create the glfw window
-	// (if we'd do it later we'd run into a race condition)
-	primaryScreen, err := snap.GetPrimary()
+	if *flagSeed < 0 || *flagSeed >= maxSeed {
+		log.Fatalf("-seed must be between 1 and %d, or 0 for a random seed", maxSeed-1)
+	}
+	seed := pickSeed(*flagSeed)
+	log.Infof("using seed %d", seed)
+	effect.Seed(seed)
+
+	bg, err := parseBackground(*flagBackground)
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
+	}
+
+	cfg, err := config.Load(*flagConfig)
+	if err != nil {
+		log.Fatalf("failed to load config %s: %s", *flagConfig, err)
+	}
+	if cfg.Outputs == nil {
+		cfg.Outputs = map[string]config.Output{}
+	}
+	def := cfg.Outputs[config.DefaultOutput]
+	if *flagOverlay != "" {
+		def.Overlay = *flagOverlay
+	}
+	if *flagEffect != "" {
+		def.Effect = *flagEffect
+	}
+	cfg.Outputs[config.DefaultOutput] = def
+	// parameters from the command line win over every output section
+	for name, out := range cfg.Outputs {
+		if out.Params == nil {
+			out.Params = map[string]config.ParamValue{}
+		}
+		for param, values := range flagParams {
+			out.Params[param] = values
+		}
+		cfg.Outputs[name] = out
+	}
+	if command == commandRender {
+		opts := renderOptions{input: *flagInput, out: *flagOut, frames: *flagFrames, fps: *flagFPS, seed: seed}
+		if err := renderFrames(cfg, opts); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	primaryScreenSnapshot, err := primaryScreen.Capture()
+
+	redact, err := newRedactor(cfg.Redact)
+	if err != nil {
+		log.Fatalf("failed to setup redaction: %s", err)
+	}
+
+	if command == commandPreview {
+		if err := preview(cfg, bg, redact, *flagWatch, seed); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	events, err := audit.NewFromConfig(cfg.Audit)
+	if err != nil {
+		log.Fatalf("failed to setup audit log: %s", err)
+	}
+	defer events.Close()
+	hooks := hook.NewRunner(cfg.Hooks)
+	// the X event loop may still fire hooks while we wait for them
+	defer func() {
+		hooks.Close()
+		hooks.Wait()
+	}()
+	events.Emit(audit.LockStarted, 0)
+	hooks.Run(hook.PreLock, nil)
+
+	xw, err := xw.New()
 	if err != nil {
 		panic(err)
 	}
+	outputs, err := xw.Outputs()
+	if err != nil {
+		log.Fatalf("failed to query outputs: %s", err)
+	}
+	if len(outputs) == 0 {
+		log.Fatal("no active outputs found")
+	}
+	bounds := outputBounds(outputs)
+
+	// capture screen before we create the glfw window
+	// (if we'd do it later we'd run into a race condition)
+	snapshots, err := bg.images(xw, outputs, redact)
+	if err != nil {
+		log.Fatalf("failed to create background: %s", err)
+	}
 
 	if err := glfw.Init(); err != nil {
 		log.Fatalln("failed to inifitialize glfw:", err)
 	}
 	defer glfw.Terminate()
 
-	// setup monitor & window
-	primaryMonitor := glfw.GetPrimaryMonitor()
-	videoMode := primaryMonitor.GetVideoMode()
-	mons := glfw.GetMonitors()
-	glfw.WindowHint(glfw.Resizable, glfw.False)
-	glfw.WindowHint(glfw.ContextVersionMajor, 4)
-	glfw.WindowHint(glfw.ContextVersionMinor, 1)
-	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
-	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
-	window, err := glfw.CreateWindow(videoMode.Width, videoMode.Height, "gllock", nil, nil)
+	// one window spans all outputs,
+	// it is mapped by xw.Fullscreen after it became override-redirect
+	window := createWindow(bounds.Dx(), bounds.Dy(), false)
+
+	r, err := newRenderer(bounds, outputs, snapshots, cfg, xw.XftDPI())
 	if err != nil {
-		panic(err)
+		log.Fatal(err)
+	}
+	defer r.destroy()
+	r.seed = seedUniform(seed)
+
+	var reloads <-chan string
+	if *flagWatch {
+		reloads, err = effect.Watch(r.effectPaths())
+		if err != nil {
+			log.Fatalf("failed to watch effects: %s", err)
+		}
 	}
-	window.MakeContextCurrent()
 
-	if err := gl.Init(); err != nil {
-		panic(err)
+	auth := &authTracker{}
+	xw.MaxAttempts = cfg.Lockout.Attempts
+	xw.LockoutDuration = time.Duration(cfg.Lockout.Duration)
+	xw.OnKeyPress = auth.key
+	xw.OnAuthFailure = func(attempts int) {
+		auth.fail()
+		events.Emit(audit.AuthFailure, attempts)
+		hooks.Fire(hook.AuthFailure, map[string]string{
+			"GLLOCK_ATTEMPTS": strconv.Itoa(attempts),
+		})
+	}
+	xw.OnLockout = func(attempts int, until time.Time) {
+		events.Emit(audit.Lockout, attempts)
+		hooks.Fire(hook.Lockout, map[string]string{
+			"GLLOCK_ATTEMPTS":      strconv.Itoa(attempts),
+			"GLLOCK_LOCKOUT_UNTIL": strconv.FormatInt(until.Unix(), 10),
+		})
+	}
+	xw.OnGrabLost = func() {
+		events.Emit(audit.GrabLost, 0)
+	}
+	for keys, command := range cfg.Keybindings {
+		keys, command := keys, command
+		err := xw.Bind(keys, func() {
+			hooks.Exec(hook.Keybinding, command, map[string]string{"GLLOCK_KEY": keys})
+		})
+		if err != nil {
+			log.Fatalf("invalid keybinding %s: %s", keys, err)
+		}
 	}
 
-	xw, err := xw.New()
+	err = xw.LoadCursor(*flagCursor)
 	if err != nil {
-		panic(err)
+		log.Fatalf("failed to load cursor %s: %s", *flagCursor, err)
 	}
 
-	// race-condition? window might not yet be there?
-	err = xw.Fullscreen("gllock")
+	fullscreen := func(bounds image.Rectangle) error {
+		return xw.Fullscreen(xproto.Window(window.GetX11Window()), bounds)
+	}
+	err = fullscreen(bounds)
 	if err != nil {
 		panic(err)
 	}
+	// outputs that show up while locked are covered immediately
+	outputChanges, err := xw.WatchOutputs(outputs)
+	if err != nil {
+		log.Fatalf("failed to watch outputs: %s", err)
+	}
+	defer xw.DestroyCovers()
+	go func() {
+		// stay on top of notifications and other override-redirect windows
+		for range time.Tick(time.Second) {
+			if err := xw.Restack(); err != nil {
+				log.Warnf("failed to restack windows: %s", err)
+			}
+		}
+	}()
+
 	err = xw.GrabInput()
 	if err != nil {
 		log.Fatal(err)
 	}
+	events.Emit(audit.GrabAcquired, 0)
+	hooks.Fire(hook.PostGrab, nil)
+	// the audit log is closed on return, wait for the events of clearSecrets first
+	var cleared sync.WaitGroup
+	cleared.Add(1)
+	go func() {
+		defer cleared.Done()
+		clearSecrets(cfg.Secrets, xw, events)
+	}()
+	defer cleared.Wait()
 
 	// SIGINT handler
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
+	interrupted := make(chan os.Signal, 1)
 	go func() {
-		for range c {
-			log.Debugf("received SIGINT, closing window")
-			window.SetShouldClose(true)
-			return
-		}
+		sig := <-c
+		log.Debugf("received %s, closing window", sig)
+		interrupted <- sig
+		window.SetShouldClose(true)
 	}()
 
 	// password-matcher goroutine
+	summary := make(chan []string, 1)
 	go func() {
 		done := xw.PasswordMatch()
 		for {
 			select {
 			case <-done:
+				auth.unlock()
+				// the desktop is usable while the summary frame is shown
+				xw.UngrabInput()
+				xw.DestroyCovers()
+				lines := summaryLines(xw.FailedAttempts())
+				if *flagSummary == summaryFrame && len(lines) > 0 {
+					summary <- lines
+					time.AfterFunc(summaryFrameDuration, func() {
+						window.SetShouldClose(true)
+					})
+					return
+				}
 				window.SetShouldClose(true)
 				return
 			}
 		}
 	}()
 
-	// overlay non-primary monitors with black
-	for _, monitor := range mons {
-		if monitor.GetName() != primaryMonitor.GetName() {
-			x, y := monitor.GetPos()
-			videoMode := monitor.GetVideoMode()
-			log.Debugf("overlaying monitor %s, videoMode: %#v pos: %d, %d", monitor.GetName(), videoMode, x, y)
-			xw.Overlay(x, y, videoMode.Width, videoMode.Height)
-		}
-	}
-
 	// this runs until our glfw window receives a ShouldClose() call
-	err = programLoop(window, primaryScreen, primaryScreenSnapshot, *flagOverlay, *videoMode)
+	err = programLoop(window, r, summary, xw.Pointer, auth.snapshot, outputChanges, reloads, fullscreen)
 	if err != nil {
 		log.Fatal(err)
 	}
-}
-
-func programLoop(window *glfw.Window, primaryScreen snap.Screen, snapshot *image.RGBA, overlay string, videoMode glfw.VidMode) error {
-	var overlayPlane *gfx.Mesh
-	var overlayTex *gfx.Texture
-	if overlay != "" {
-		overlayTex = gfx.MustTextureFromFile(overlay, gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
-		overlayPlane = gfx.NewMesh(gvd.PlaneVertices, gvd.PlaneIndices, []*gfx.Texture{overlayTex})
+	showSummary(*flagSummary, summaryLines(xw.FailedAttempts()))
+	// post-unlock hooks undo what pre-lock hooks did, e.g. unmute the
+	// microphone. They only run if the password was accepted, not if
+	// gllock was interrupted.
+	if auth.snapshot().unlocked.IsZero() {
+		reason := "lock window closed"
+		select {
+		case sig := <-interrupted:
+			reason = fmt.Sprintf("received signal %s", sig)
+		default:
+		}
+		events.Abort(reason)
+		return
 	}
+	events.Emit(audit.Unlocked, 0)
+	hooks.Fire(hook.PostUnlock, nil)
+}
 
-	log.Debugf("%#v", primaryScreen)
-	window.SetPos(primaryScreen.X, primaryScreen.Y)
-	box := packr.NewBox("shaders")
-	screenTex := gfx.MustTexture(snapshot, gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
-	regVert, err := box.FindString("regular.vert")
-	if err != nil {
-		return err
+// createWindow creates a window with a GL 4.1 core context and makes it current.
+// visible windows can be resized, like the preview.
+func createWindow(width, height int, visible bool) *glfw.Window {
+	hint := glfw.False
+	if visible {
+		hint = glfw.True
 	}
-	regFrag, err := box.FindString("regular.frag")
+	glfw.WindowHint(glfw.Resizable, hint)
+	glfw.WindowHint(glfw.Visible, hint)
+	glfw.WindowHint(glfw.ContextVersionMajor, 4)
+	glfw.WindowHint(glfw.ContextVersionMinor, 1)
+	glfw.WindowHint(glfw.OpenGLProfile, glfw.OpenGLCoreProfile)
+	glfw.WindowHint(glfw.OpenGLForwardCompatible, glfw.True)
+	window, err := glfw.CreateWindow(width, height, "gllock", nil, nil)
 	if err != nil {
-		return err
+		panic(err)
 	}
-	planeProg := gfx.MustMakeProgram(regVert, regFrag)
-	screenshotPlane := gfx.NewMesh(gvd.PlaneVertices, gvd.PlaneIndices, []*gfx.Texture{screenTex})
+	window.MakeContextCurrent()
 
-	fbo := gfx.MustFramebuffer(videoMode.Width, videoMode.Height)
-	defer fbo.Destroy()
-	fxVert, err := box.FindString("fx.vert")
-	if err != nil {
-		return err
-	}
-	fxFrag, err := box.FindString("fx.frag")
-	if err != nil {
-		return err
+	if err := gl.Init(); err != nil {
+		panic(err)
 	}
-	fxProg := gfx.MustMakeProgram(fxVert, fxFrag)
-	fxPlane := gfx.NewMesh(gvd.InvertedTexPlaneVertices, gvd.PlaneIndices, []*gfx.Texture{fbo.Texture})
-
-	gl.Enable(gl.BLEND)
-	gl.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
-
-	var time, delta, lastTime float64
-	time = glfw.GetTime()
+	return window
+}
 
-	fxProg.Use()
-	gl.Uniform2i(fxProg.GetUniformLocation("resolution"), int32(videoMode.Height), int32(videoMode.Width))
+func programLoop(window *glfw.Window, r *renderer, summary <-chan []string, pointer func() image.Point, auth func() authState,
+	outputChanges <-chan xw.OutputChange, reloads <-chan string, fullscreen func(image.Rectangle) error) error {
+	var frames int
+	// due is when the next frame should be drawn, in seconds of the clock
+	due := r.clock.elapsed()
 
 	for !window.ShouldClose() {
-		time = glfw.GetTime()
-		delta = time - lastTime
-
-		if delta < maxTime {
-			continue
+		if wait := due - r.clock.elapsed(); wait > 0 {
+			time.Sleep(time.Duration(wait * float64(time.Second)))
 		}
-
-		lastTime = time
-
-		// render to framebuffer
-		fbo.Bind()
-		planeProg.Use()
-		screenshotPlane.Draw(planeProg)
-		fbo.Unbind()
-
-		// render framebuffer to screen
-		fxProg.Use()
-		gl.Uniform1f(fxProg.GetUniformLocation("time"), float32(glfw.GetTime()))
-		fxPlane.Draw(fxProg)
-
-		if overlayTex != nil && overlayPlane != nil {
-			// render image to screen
-			planeProg.Use()
-			gl.Viewport(int32(videoMode.Width/2)-overlayTex.Width/2, int32(videoMode.Height/2)-overlayTex.Height/2, overlayTex.Width, overlayTex.Height)
-			overlayPlane.Draw(planeProg)
-			gl.Viewport(0, 0, int32(videoMode.Width), int32(videoMode.Height))
+		// a late frame does not make the following frames come faster
+		due = math.Max(due+maxTime, r.clock.elapsed())
+
+		select {
+		case change := <-outputChanges:
+			bounds := r.bounds
+			newBounds := r.updateOutputs(change.Outputs)
+			if change.Err != nil {
+				// uncovered outputs lie within the bounds of all outputs,
+				// the raised window keeps them locked
+				log.Errorf("could not cover outputs, spanning the lock window over them: %s", change.Err)
+			}
+			if newBounds != bounds || change.Err != nil {
+				log.Debugf("resizing window from %v to %v", bounds, newBounds)
+				if err := fullscreen(newBounds); err != nil {
+					return err
+				}
+			}
+		case lines := <-summary:
+			r.setSummary(lines)
+		case path := <-reloads:
+			r.reload(path)
+		default:
 		}
 
+		r.draw(frames, pointer(), auth())
 		window.SwapBuffers()
+		frames++
 	}
 	return nil
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"time"
+
+	"github.com/BurntSushi/xgbutil/xgraphics"
+
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/gfx"
+	"github.com/moolen/gllock/gfx/headless"
+	"github.com/moolen/gllock/xw"
+	log "github.com/sirupsen/logrus"
+)
+
+// commandRender renders an effect into files without a window
+const commandRender = "render"
+
+// renderOutput is the name of the output that is rendered,
+// the config section of that name is used if there is one
+const renderOutput = "render"
+
+// renderDate is passed to the effects instead of the current date,
+// so every render of an effect looks the same
+var renderDate = time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+// renderOptions are the flags of gllock render
+type renderOptions struct {
+	input  string
+	out    string
+	frames int
+	fps    int
+	seed   int64
+}
+
+// renderFrames applies the effect to the input image in a headless GL
+// context and writes the frames to opts.out. Frame i is rendered at
+// i/fps seconds, independent of how long rendering takes.
+func renderFrames(cfg *config.Config, opts renderOptions) error {
+	if opts.input == "" || opts.out == "" {
+		return fmt.Errorf("render needs -input and -out")
+	}
+	if opts.frames <= 0 || opts.fps <= 0 {
+		return fmt.Errorf("-frames and -fps must be positive")
+	}
+	file, err := os.Open(opts.input)
+	if err != nil {
+		return err
+	}
+	src, _, err := image.Decode(file)
+	file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to decode %s: %s", opts.input, err)
+	}
+	size := src.Bounds().Size()
+	output := xw.Output{Name: renderOutput, Rect: image.Rect(0, 0, size.X, size.Y), Primary: true}
+	snapshot := toBGRX(nil, cover(src, size))
+
+	ctx, err := headless.New()
+	if err != nil {
+		return fmt.Errorf("failed to create GL context: %s", err)
+	}
+	defer ctx.Destroy()
+	target, err := gfx.NewFramebuffer(size.X, size.Y)
+	if err != nil {
+		return err
+	}
+	defer target.Destroy()
+
+	r, err := newRenderer(output.Rect, []xw.Output{output}, []*xgraphics.Image{snapshot}, cfg, 0)
+	if err != nil {
+		return err
+	}
+	defer r.destroy()
+	r.setTarget(target)
+	clock := &stepClock{date: renderDate, step: 1 / float64(opts.fps)}
+	r.clock = clock
+	r.seed = seedUniform(opts.seed)
+
+	w, err := newFrameWriter(opts.out, size, opts.fps)
+	if err != nil {
+		return err
+	}
+	// the pointer rests in the middle of the output
+	pointer := image.Pt(size.X/2, size.Y/2)
+	for i := 0; i < opts.frames; i++ {
+		r.draw(i, pointer, authState{})
+		if err := w.WriteFrame(target.Image()); err != nil {
+			w.Close()
+			return err
+		}
+		clock.advance()
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	log.Infof("rendered %d frames of %s to %s", opts.frames, r.primary().settings.Effect, opts.out)
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/go-gl/glfw/v3.1/glfw"
+
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/effect"
+	"github.com/moolen/gllock/gfx"
+	"github.com/moolen/gllock/xw"
+	log "github.com/sirupsen/logrus"
+)
+
+// commandPreview shows the lock screen in a window without locking
+const commandPreview = "preview"
+
+// previewKeys is shown below the status of the preview
+const previewKeys = "left/right effect  tab param  up/down value  f fail  t type  enter unlock  r reset  q quit"
+
+// previewer is the state of gllock preview
+type previewer struct {
+	r      *renderer
+	screen *screen
+	auth   *authTracker
+	// effects are the names that can be cycled through
+	effects []string
+	current int
+	// param is the index of the selected parameter
+	param int
+}
+
+// preview renders the primary output in a resizable window until it is closed.
+// It neither grabs the input nor checks passwords, auth events are faked
+// with the keyboard.
+func preview(cfg *config.Config, bg background, redact *redactor, watch bool, seed int64) error {
+	x, err := xw.New()
+	if err != nil {
+		return err
+	}
+	outputs, err := x.Outputs()
+	if err != nil {
+		return fmt.Errorf("failed to query outputs: %s", err)
+	}
+	if len(outputs) == 0 {
+		return fmt.Errorf("no active outputs found")
+	}
+	output := outputs[0]
+	for _, o := range outputs {
+		if o.Primary {
+			output = o
+		}
+	}
+	snapshots, err := bg.images(x, []xw.Output{output}, redact)
+	if err != nil {
+		return fmt.Errorf("failed to create background: %s", err)
+	}
+	// the output fills the window, which starts at half its size
+	output.Rect = output.Rect.Sub(output.Rect.Min)
+
+	if err := glfw.Init(); err != nil {
+		return fmt.Errorf("failed to initialize glfw: %s", err)
+	}
+	defer glfw.Terminate()
+	window := createWindow(output.Rect.Dx()/2, output.Rect.Dy()/2, true)
+	window.SetTitle("gllock preview")
+	glfw.SwapInterval(1)
+
+	r, err := newRenderer(output.Rect, []xw.Output{output}, snapshots, cfg, x.XftDPI())
+	if err != nil {
+		return err
+	}
+	defer r.destroy()
+	r.seed = seedUniform(seed)
+	p := &previewer{r: r, screen: r.screens[0], auth: &authTracker{}}
+	if err := p.listEffects(cfg); err != nil {
+		return err
+	}
+
+	var reloads <-chan string
+	if watch {
+		reloads, err = effect.Watch(p.effectPaths())
+		if err != nil {
+			return fmt.Errorf("failed to watch effects: %s", err)
+		}
+	}
+
+	resize := func(width, height int) {
+		if width == 0 || height == 0 {
+			// minimized
+			return
+		}
+		o := output
+		o.Rect = image.Rect(0, 0, width, height)
+		r.updateOutputs([]xw.Output{o})
+	}
+	resize(window.GetFramebufferSize())
+	window.SetFramebufferSizeCallback(func(_ *glfw.Window, width, height int) {
+		resize(width, height)
+	})
+	window.SetKeyCallback(func(w *glfw.Window, key glfw.Key, _ int, action glfw.Action, mods glfw.ModifierKey) {
+		if action != glfw.Press && action != glfw.Repeat {
+			return
+		}
+		p.key(w, key, mods)
+	})
+	p.showStatus()
+
+	var frames int
+	for !window.ShouldClose() {
+		glfw.PollEvents()
+		select {
+		case path := <-reloads:
+			r.reload(path)
+		default:
+		}
+		r.draw(frames, framebufferCursor(window), p.auth.snapshot())
+		window.SwapBuffers()
+		frames++
+	}
+	return nil
+}
+
+// framebufferCursor returns the pointer in framebuffer pixels, which is
+// the pointer on the output. GetCursorPos is in screen coordinates,
+// which differ from pixels on HiDPI screens.
+func framebufferCursor(window *glfw.Window) image.Point {
+	cx, cy := window.GetCursorPos()
+	w, h := window.GetSize()
+	fw, fh := window.GetFramebufferSize()
+	if w == 0 || h == 0 {
+		return image.Pt(int(cx), int(cy))
+	}
+	return image.Pt(int(cx*float64(fw)/float64(w)), int(cy*float64(fh)/float64(h)))
+}
+
+// listEffects collects all effects and the pipelines of the config.
+// The effect of the output is selected.
+func (p *previewer) listEffects(cfg *config.Config) error {
+	effects, err := effect.List()
+	if err != nil {
+		return err
+	}
+	for _, e := range effects {
+		p.effects = append(p.effects, e.Name)
+	}
+	var pipelines []string
+	for name := range cfg.Pipelines {
+		pipelines = append(pipelines, name)
+	}
+	sort.Strings(pipelines)
+	p.effects = append(p.effects, pipelines...)
+
+	p.current = -1
+	for i, name := range p.effects {
+		if name == p.screen.settings.Effect {
+			p.current = i
+		}
+	}
+	if p.current < 0 {
+		// random or a path, it is cycled like any other effect
+		p.effects = append([]string{p.screen.settings.Effect}, p.effects...)
+		p.current = 0
+	}
+	return nil
+}
+
+// effectPaths returns the files of all effects that can be selected
+func (p *previewer) effectPaths() []string {
+	var paths []string
+	effects, err := effect.List()
+	if err != nil {
+		log.Warnf("failed to list effects: %s", err)
+	}
+	for _, e := range effects {
+		if e.Path != "" {
+			paths = append(paths, e.Path)
+		}
+	}
+	// the effect of the output may be a path that is not in the effect directories
+	return append(paths, p.r.effectPaths()...)
+}
+
+// key handles the shortcuts of the preview
+func (p *previewer) key(w *glfw.Window, key glfw.Key, mods glfw.ModifierKey) {
+	switch key {
+	case glfw.KeyEscape, glfw.KeyQ:
+		w.SetShouldClose(true)
+		return
+	case glfw.KeyRight, glfw.KeyN:
+		p.cycle(1)
+	case glfw.KeyLeft, glfw.KeyP:
+		p.cycle(-1)
+	case glfw.KeyTab:
+		if params := p.params(); len(params) > 0 {
+			step := 1
+			if mods&glfw.ModShift != 0 {
+				step = -1
+			}
+			p.param = (p.param + step + len(params)) % len(params)
+		}
+	case glfw.KeyUp:
+		p.adjust(1)
+	case glfw.KeyDown:
+		p.adjust(-1)
+	case glfw.KeyF:
+		p.auth.fail()
+		log.Infof("fake failed attempt")
+	case glfw.KeyT:
+		p.auth.key()
+	case glfw.KeyEnter, glfw.KeyKPEnter:
+		p.auth.unlock()
+		log.Infof("fake unlock")
+	case glfw.KeyR:
+		p.auth.reset()
+		// rebuilding the pipeline restores the parameters of the config
+		p.cycle(0)
+		log.Infof("reset parameters and auth events")
+	default:
+		return
+	}
+	p.showStatus()
+}
+
+// cycle selects the effect step positions away, 0 reloads the current one.
+// Parameters that were changed in the preview are dropped.
+// Effects that fail to compile are reported and skipped.
+func (p *previewer) cycle(step int) {
+	for range p.effects {
+		p.current = (p.current + step + len(p.effects)) % len(p.effects)
+		name := p.effects[p.current]
+		err := p.r.setEffect(p.screen, name)
+		if err == nil {
+			p.param = 0
+			p.r.setError(nil)
+			return
+		}
+		log.Errorf("failed to load effect %s: %s", name, err)
+		p.r.setError(err)
+		if step == 0 {
+			return
+		}
+	}
+}
+
+// params returns the parameters of all passes, each name once
+func (p *previewer) params() []*gfx.Param {
+	if p.screen.pipeline == nil {
+		return nil
+	}
+	var params []*gfx.Param
+	seen := map[string]bool{}
+	for _, pass := range p.screen.pipeline.Passes() {
+		for _, param := range pass.Program.Params {
+			if !seen[param.Name] {
+				seen[param.Name] = true
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
+
+// values returns the current values of param
+func (p *previewer) values(param *gfx.Param) []float32 {
+	if values, ok := p.screen.params[param.Name]; ok {
+		return values
+	}
+	return param.Default
+}
+
+// adjust changes every component of the selected parameter by a
+// twentieth of its range, or a tenth of its value if it is unbounded
+func (p *previewer) adjust(direction float32) {
+	params := p.params()
+	if len(params) == 0 {
+		return
+	}
+	param := params[p.param%len(params)]
+	current := p.values(param)
+	values := make([]float32, len(current))
+	for i, v := range current {
+		step := float32(math.Max(math.Abs(float64(v))/10, 0.01))
+		if param.Min != nil && param.Max != nil {
+			step = (*param.Max - *param.Min) / 20
+		}
+		if param.Type == "int" && step < 1 {
+			step = 1
+		}
+		v += direction * step
+		if param.Min != nil && v < *param.Min {
+			v = *param.Min
+		}
+		if param.Max != nil && v > *param.Max {
+			v = *param.Max
+		}
+		values[i] = v
+	}
+	p.screen.params[param.Name] = values
+}
+
+// showStatus shows the effect, the parameters and the auth events
+// in the window and on stderr
+func (p *previewer) showStatus() {
+	lines := []string{"effect " + p.effects[p.current]}
+	for i, param := range p.params() {
+		marker := " "
+		if i == p.param%len(p.params()) {
+			marker = ">"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s = %s %s", marker, param.Name, formatValues(p.values(param)), param.Range()))
+	}
+	auth := p.auth.snapshot()
+	lines = append(lines, fmt.Sprintf("failed attempts %d", auth.attempts))
+	if !auth.unlocked.IsZero() {
+		lines = append(lines, "unlocked")
+	}
+	log.Info(strings.Join(lines, ", "))
+	p.r.setStatus(append(lines, previewKeys))
+}
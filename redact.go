@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"regexp"
+
+	"github.com/BurntSushi/xgbutil/xgraphics"
+
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/xw"
+	log "github.com/sirupsen/logrus"
+)
+
+// redactFillBlur blurs redacted windows instead of filling them with a colour
+const redactFillBlur = "blur"
+
+// redactBlurRadius is larger than minBlurRadius, because
+// windows are usually smaller than outputs and full of text
+const redactBlurRadius = 2 * minBlurRadius
+
+// redactor blanks the windows that match one of its patterns
+type redactor struct {
+	classes []*regexp.Regexp
+	titles  []*regexp.Regexp
+	blur    bool
+	// color is used when blur is not set
+	color color.RGBA
+}
+
+func newRedactor(cfg config.Redact) (*redactor, error) {
+	r := &redactor{blur: cfg.Fill == "" || cfg.Fill == redactFillBlur}
+	if !r.blur {
+		c, err := parseColor(cfg.Fill)
+		if err != nil {
+			return nil, err
+		}
+		r.color = c
+	}
+	for _, pattern := range cfg.Classes {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid class pattern %s: %s", pattern, err)
+		}
+		r.classes = append(r.classes, re)
+	}
+	for _, pattern := range cfg.Titles {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid title pattern %s: %s", pattern, err)
+		}
+		r.titles = append(r.titles, re)
+	}
+	return r, nil
+}
+
+// enabled reports whether any pattern is configured
+func (r *redactor) enabled() bool {
+	return len(r.classes) > 0 || len(r.titles) > 0
+}
+
+func (r *redactor) matches(c xw.Client) bool {
+	for _, re := range r.classes {
+		if re.MatchString(c.Class) || re.MatchString(c.Instance) {
+			return true
+		}
+	}
+	for _, re := range r.titles {
+		if re.MatchString(c.Title) {
+			return true
+		}
+	}
+	return false
+}
+
+// redact fills the visible region of every matching client
+// in the snapshots of the outputs
+func (r *redactor) redact(clients []xw.Client, outputs []xw.Output, snapshots []*xgraphics.Image) {
+	for i, c := range clients {
+		if !r.matches(c) {
+			continue
+		}
+		log.Debugf("redacting window %s (%s) at %v", c.Title, c.Class, c.Rect)
+		for _, area := range visible(c.Rect, clients[i+1:]) {
+			for j, output := range outputs {
+				part := area.Intersect(output.Rect)
+				if part.Empty() {
+					continue
+				}
+				// snapshots start at the top left of the output
+				part = part.Sub(output.Rect.Min)
+				if r.blur {
+					blur(snapshots[j], part, redactBlurRadius)
+				} else {
+					fill(snapshots[j], part, r.color)
+				}
+			}
+		}
+	}
+}
+
+// blank fills the snapshots completely. It is used when the windows
+// can't be listed, so a sensitive window is never shown.
+func (r *redactor) blank(snapshots []*xgraphics.Image) {
+	for _, img := range snapshots {
+		fill(img, img.Rect, r.color)
+	}
+}
+
+// visible returns the parts of rect that are not covered by the windows above
+func visible(rect image.Rectangle, above []xw.Client) []image.Rectangle {
+	areas := []image.Rectangle{rect}
+	for _, c := range above {
+		var rest []image.Rectangle
+		for _, area := range areas {
+			rest = append(rest, subtract(area, c.Rect)...)
+		}
+		areas = rest
+	}
+	return areas
+}
+
+// subtract returns up to four rectangles that cover a but not b
+func subtract(a, b image.Rectangle) []image.Rectangle {
+	in := a.Intersect(b)
+	if in.Empty() {
+		return []image.Rectangle{a}
+	}
+	var rest []image.Rectangle
+	for _, r := range []image.Rectangle{
+		image.Rect(a.Min.X, a.Min.Y, a.Max.X, in.Min.Y),
+		image.Rect(a.Min.X, in.Max.Y, a.Max.X, a.Max.Y),
+		image.Rect(a.Min.X, in.Min.Y, in.Min.X, in.Max.Y),
+		image.Rect(in.Max.X, in.Min.Y, a.Max.X, in.Max.Y),
+	} {
+		if !r.Empty() {
+			rest = append(rest, r)
+		}
+	}
+	return rest
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"image"
+	"reflect"
+	"testing"
+
+	"github.com/moolen/gllock/xw"
+)
+
+func TestSubtract(t *testing.T) {
+	a := image.Rect(0, 0, 100, 100)
+	for _, tc := range []struct {
+		name string
+		b    image.Rectangle
+		want []image.Rectangle
+	}{
+		{"disjoint", image.Rect(200, 200, 300, 300), []image.Rectangle{a}},
+		{"touching", image.Rect(100, 0, 200, 100), []image.Rectangle{a}},
+		{"covered", image.Rect(-10, -10, 110, 110), nil},
+		{"top half", image.Rect(0, 0, 100, 50), []image.Rectangle{image.Rect(0, 50, 100, 100)}},
+		{"right edge", image.Rect(80, -10, 120, 110), []image.Rectangle{image.Rect(0, 0, 80, 100)}},
+		{"center", image.Rect(40, 40, 60, 60), []image.Rectangle{
+			image.Rect(0, 0, 100, 40),
+			image.Rect(0, 60, 100, 100),
+			image.Rect(0, 40, 40, 60),
+			image.Rect(60, 40, 100, 60),
+		}},
+	} {
+		if got := subtract(a, tc.b); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: subtract(%v, %v) = %v, want %v", tc.name, a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestVisible(t *testing.T) {
+	rect := image.Rect(0, 0, 100, 100)
+	client := func(x0, y0, x1, y1 int) xw.Client {
+		return xw.Client{Rect: image.Rect(x0, y0, x1, y1)}
+	}
+	for _, tc := range []struct {
+		name  string
+		above []xw.Client
+		// area is the number of visible pixels
+		area int
+	}{
+		{"on top", nil, 100 * 100},
+		{"elsewhere", []xw.Client{client(200, 0, 300, 100)}, 100 * 100},
+		{"left half covered", []xw.Client{client(0, 0, 50, 100)}, 50 * 100},
+		{"overlapping windows", []xw.Client{client(0, 0, 60, 100), client(40, 0, 100, 50)}, 40 * 50},
+		{"stacked windows", []xw.Client{client(0, 0, 50, 100), client(0, 0, 50, 100)}, 50 * 100},
+		{"fully covered", []xw.Client{client(0, 0, 50, 100), client(50, 0, 100, 100)}, 0},
+	} {
+		areas := visible(rect, tc.above)
+		var area int
+		for i, a := range areas {
+			if !a.In(rect) {
+				t.Errorf("%s: %v is outside of %v", tc.name, a, rect)
+			}
+			for _, c := range tc.above {
+				if a.Overlaps(c.Rect) {
+					t.Errorf("%s: %v overlaps the window at %v", tc.name, a, c.Rect)
+				}
+			}
+			for _, b := range areas[i+1:] {
+				if a.Overlaps(b) {
+					t.Errorf("%s: %v overlaps %v", tc.name, a, b)
+				}
+			}
+			area += a.Dx() * a.Dy()
+		}
+		if area != tc.area {
+			t.Errorf("%s: %d pixels are visible, want %d", tc.name, area, tc.area)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/xgbutil/xgraphics"
+	"github.com/go-gl/gl/v4.1-core/gl"
+	"github.com/gobuffalo/packr"
+
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/effect"
+	"github.com/moolen/gllock/gfx"
+	"github.com/moolen/gllock/xw"
+	log "github.com/sirupsen/logrus"
+)
+
+// renderer draws the lock screens of all outputs into one window
+// that spans the bounds of all outputs
+type renderer struct {
+	bounds    image.Rectangle
+	screens   []*screen
+	box       packr.Box
+	planeProg *gfx.Program
+	programs  map[string]*gfx.Program
+	pipelines map[string][]config.Pass
+	overlays  map[string]*sprite
+	summary   *sprite
+	// paths maps effect names to the absolute path of their file,
+	// built-in effects are left out
+	paths map[string]string
+	// errorText shows why the last reload failed
+	errorText *sprite
+	// status shows the effect and parameters in the preview
+	status *sprite
+	// target is the framebuffer everything is drawn into, nil for the window
+	target *gfx.Framebuffer
+	// clock drives the time and date of the effects
+	clock clock
+	// seed is passed to the effects, see seedUniform
+	seed float32
+}
+
+// newRenderer creates a screen for every output that shows the
+// snapshot of the output with the effect, overlay and text from cfg.
+// xftDPI is the Xft.dpi resource or 0 if unset.
+func newRenderer(bounds image.Rectangle, outputs []xw.Output, snapshots []*xgraphics.Image, cfg *config.Config, xftDPI float64) (*renderer, error) {
+	r := &renderer{
+		bounds:    bounds,
+		box:       packr.NewBox("shaders"),
+		programs:  map[string]*gfx.Program{},
+		pipelines: cfg.Pipelines,
+		overlays:  map[string]*sprite{},
+		paths:     map[string]string{},
+		clock:     wallClock{},
+	}
+	regVert, err := r.box.FindString("regular.vert")
+	if err != nil {
+		return nil, err
+	}
+	regFrag, err := r.box.FindString("regular.frag")
+	if err != nil {
+		return nil, err
+	}
+	r.planeProg = gfx.MustMakeProgram(regVert, regFrag)
+
+	for i, output := range outputs {
+		settings := cfg.Output(output.Name)
+		scale := settings.Scale
+		if scale <= 0 {
+			scale = outputScale(output, xftDPI)
+		}
+		log.Debugf("rendering output %s at %v with scale %.2f: %#v", output.Name, output.Rect, scale, settings)
+		s := newScreen(output, settings, snapshots[i], scale)
+		s.pipeline, err = r.pipeline(settings.Effect, s)
+		if err != nil {
+			return nil, err
+		}
+		if settings.Overlay != "" {
+			s.overlay = r.overlay(settings.Overlay)
+		}
+		if settings.Text != "" {
+			s.text = newSprite(gfx.MustTextTexture(strings.Split(settings.Text, "\n"), color.White, color.Transparent))
+		}
+		r.screens = append(r.screens, s)
+	}
+
+	gl.Enable(gl.BLEND)
+	gl.BlendFunc(gl.ONE, gl.ONE_MINUS_SRC_ALPHA)
+	gl.ClearColor(0, 0, 0, 1)
+	return r, nil
+}
+
+// effect compiles the effect program once and returns it.
+// name is a built-in effect, an effect from the effect directories or a path.
+func (r *renderer) effect(name string) (*gfx.Program, error) {
+	if prog, ok := r.programs[name]; ok {
+		return prog, nil
+	}
+	e, err := effect.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("loaded effect %s", e)
+	prog, err := e.Program()
+	if err != nil {
+		return nil, err
+	}
+	r.programs[name] = prog
+	if e.Path != "" {
+		r.paths[name], err = filepath.Abs(e.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return prog, nil
+}
+
+// effectPaths returns the files of all effects in use
+func (r *renderer) effectPaths() []string {
+	var paths []string
+	for _, path := range r.paths {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// reload recompiles the effects read from path. On error the last working
+// program stays in use and the error is shown on the primary output.
+func (r *renderer) reload(path string) {
+	for name, p := range r.paths {
+		if p != path {
+			continue
+		}
+		e, err := effect.Load(name)
+		var prog *gfx.Program
+		if err == nil {
+			prog, err = e.Program()
+		}
+		if err != nil {
+			log.Errorf("failed to reload effect %s: %s", name, err)
+			r.setError(err)
+			continue
+		}
+		log.Infof("reloaded effect %s", e)
+		// pipelines keep pointers to the program, so its content is replaced
+		old := r.programs[name]
+		old.Delete()
+		*old = *prog
+		// the parameters may have changed, overrides are checked again
+		var dropped []string
+		for _, s := range r.screens {
+			if s.uses(old) {
+				dropped = append(dropped, s.checkParams()...)
+			}
+		}
+		if len(dropped) == 0 {
+			r.setError(nil)
+			continue
+		}
+		for _, msg := range dropped {
+			log.Warn(msg)
+		}
+		r.setError(errors.New(strings.Join(dropped, "\n")))
+	}
+}
+
+// setError shows err on top of the primary output, nil removes it
+func (r *renderer) setError(err error) {
+	if err == nil {
+		r.errorText = nil
+		return
+	}
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	r.errorText = newSprite(gfx.MustTextTexture(lines, color.RGBA{255, 96, 96, 255}, color.RGBA{0, 0, 0, 220}))
+}
+
+// pipeline builds the passes of the named pipeline from the config
+// or a single pass for an effect. It returns nil for the black effect.
+func (r *renderer) pipeline(name string, s *screen) (*gfx.Pipeline, error) {
+	var passes []*gfx.Pass
+	if spec, ok := r.pipelines[name]; ok {
+		for i, p := range spec {
+			prog, err := r.effect(p.Effect)
+			if err != nil {
+				return nil, err
+			}
+			if prog == nil {
+				return nil, fmt.Errorf("pipeline %s: pass %d has no shader", name, i)
+			}
+			inputs := p.Inputs
+			if len(inputs) == 0 {
+				inputs = []string{gfx.InputPrevious}
+			}
+			if len(inputs) > effect.MaxInputs {
+				return nil, fmt.Errorf("pipeline %s: pass %d has more than %d inputs", name, i, effect.MaxInputs)
+			}
+			passes = append(passes, &gfx.Pass{Name: p.Name, Program: prog, Inputs: inputs})
+		}
+	} else {
+		prog, err := r.effect(name)
+		if err != nil || prog == nil {
+			return nil, err
+		}
+		passes = []*gfx.Pass{{Program: prog, Inputs: []string{gfx.InputScreenshot}}}
+	}
+	for param, values := range s.settings.Params {
+		found := false
+		for _, pass := range passes {
+			if p := pass.Program.Param(param); p != nil {
+				if err := p.Check(values); err != nil {
+					return nil, fmt.Errorf("output %s: %s", s.output.Name, err)
+				}
+				found = true
+			}
+		}
+		if !found {
+			log.Warnf("output %s: effect %s has no parameter %s", s.output.Name, name, param)
+			continue
+		}
+		s.params[param] = values
+	}
+	p, err := gfx.NewPipeline(passes, s.fbo.Texture, s.output.Rect.Dx(), s.output.Rect.Dy())
+	if err != nil {
+		return nil, fmt.Errorf("pipeline %s: %s", name, err)
+	}
+	p.Output = r.target
+	return p, nil
+}
+
+// setEffect replaces the effect or pipeline of s.
+// The parameters of the output are checked against the new effect.
+func (r *renderer) setEffect(s *screen, name string) error {
+	params := s.params
+	s.params = map[string][]float32{}
+	p, err := r.pipeline(name, s)
+	if err != nil {
+		s.params = params
+		return err
+	}
+	if s.pipeline != nil {
+		s.pipeline.Destroy()
+	}
+	s.pipeline = p
+	s.settings.Effect = name
+	return nil
+}
+
+// setTarget draws into fb instead of the window
+func (r *renderer) setTarget(fb *gfx.Framebuffer) {
+	r.target = fb
+	for _, s := range r.screens {
+		if s.pipeline != nil {
+			s.pipeline.Output = fb
+		}
+	}
+}
+
+// overlay loads an overlay image once and returns it
+func (r *renderer) overlay(path string) *sprite {
+	if sp, ok := r.overlays[path]; ok {
+		return sp
+	}
+	r.overlays[path] = newSprite(gfx.MustTextureFromFile(path, gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE))
+	return r.overlays[path]
+}
+
+// destroy releases the framebuffers of all screens
+func (r *renderer) destroy() {
+	for _, s := range r.screens {
+		s.destroy()
+	}
+}
+
+// primary returns the screen that shows the summary
+func (r *renderer) primary() *screen {
+	for _, s := range r.screens {
+		if s.output.Primary {
+			return s
+		}
+	}
+	return r.screens[0]
+}
+
+// draw renders frame index at the time of the clock
+func (r *renderer) draw(index int, pointer image.Point, auth authState) {
+	f := frame{
+		time:    r.clock.elapsed(),
+		index:   index,
+		date:    r.clock.now(),
+		seed:    r.seed,
+		pointer: pointer,
+		auth:    auth,
+	}
+	if r.target != nil {
+		r.target.Bind()
+	}
+	// areas between outputs of mixed resolutions stay black
+	setViewport(image.Rect(0, 0, r.bounds.Dx(), r.bounds.Dy()))
+	gl.Clear(gl.COLOR_BUFFER_BIT)
+
+	for _, s := range r.screens {
+		s.draw(r.bounds, r.planeProg, f)
+	}
+	if r.summary != nil && len(r.screens) > 0 {
+		// render failed attempts on top of everything, twice the font size
+		primary := r.primary()
+		r.summary.draw(r.planeProg, primary.viewport(r.bounds), "center", 2*primary.scale)
+	}
+	if r.errorText != nil && len(r.screens) > 0 {
+		primary := r.primary()
+		r.errorText.draw(r.planeProg, primary.viewport(r.bounds), "top-left", primary.scale)
+	}
+	if r.status != nil && len(r.screens) > 0 {
+		primary := r.primary()
+		r.status.draw(r.planeProg, primary.viewport(r.bounds), "bottom-left", primary.scale)
+	}
+}
+
+// setStatus shows lines at the bottom left of the primary output, nil removes them
+func (r *renderer) setStatus(lines []string) {
+	if lines == nil {
+		r.status = nil
+		return
+	}
+	r.status = newSprite(gfx.MustTextTexture(lines, color.White, color.RGBA{0, 0, 0, 200}))
+}
+
+// setSummary shows the failed attempts on the primary output
+func (r *renderer) setSummary(lines []string) {
+	r.summary = newSprite(gfx.MustTextTexture(lines, color.White, color.RGBA{0, 0, 0, 200}))
+}
+
+// updateOutputs adapts the screens after a RandR change:
+// screens of vanished outputs are dropped and framebuffers are
+// resized when the mode of an output changed.
+// New outputs are covered by xw and not rendered.
+// It returns the new bounds of the window.
+func (r *renderer) updateOutputs(outputs []xw.Output) image.Rectangle {
+	byName := map[string]xw.Output{}
+	for _, o := range outputs {
+		byName[o.Name] = o
+	}
+	var screens []*screen
+	for _, s := range r.screens {
+		o, ok := byName[s.output.Name]
+		if !ok {
+			log.Debugf("output %s vanished", s.output.Name)
+			s.destroy()
+			continue
+		}
+		if o.Rect != s.output.Rect {
+			log.Debugf("output %s changed from %v to %v", o.Name, s.output.Rect, o.Rect)
+			s.resize(o)
+		}
+		screens = append(screens, s)
+	}
+	r.screens = screens
+	r.bounds = outputBounds(outputs)
+	return r.bounds
+}
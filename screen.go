@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/BurntSushi/xgbutil/xgraphics"
+	"github.com/go-gl/gl/v4.1-core/gl"
+
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/gfx"
+	"github.com/moolen/gllock/gfx/gvd"
+	"github.com/moolen/gllock/xw"
+)
+
+// overlayMargin is the distance of overlay and text to the edges of the output
+// at a scale of 1
+const overlayMargin = 32
+
+// baseDPI is the resolution at which overlay and text are drawn unscaled
+const baseDPI = 96.0
+
+// outputScale returns the factor by which overlay and text are scaled.
+// Xft.dpi is preferred because it is what the user configured, then
+// the physical resolution of the output. Unknown or bogus physical sizes,
+// like those reported by projectors, result in a scale of 1.
+func outputScale(output xw.Output, xftDPI float64) float64 {
+	dpi := xftDPI
+	if dpi <= 0 {
+		dpi = output.DPI()
+	}
+	scale := dpi / baseDPI
+	if scale < 1 || scale > 4 {
+		return 1
+	}
+	return scale
+}
+
+// screen is the lock screen of a single output.
+// The screenshot of the output is drawn into its own framebuffer
+// which is then rendered with the effect pipeline into the area
+// of the output inside the window.
+type screen struct {
+	output     xw.Output
+	settings   config.Output
+	screenshot *gfx.Mesh
+	fbo        *gfx.Framebuffer
+	// pipeline is nil for outputs that stay black
+	pipeline *gfx.Pipeline
+	// params override the parameters of the effects
+	params  map[string][]float32
+	overlay *sprite
+	text    *sprite
+	// scale is applied to overlay and text
+	scale float64
+}
+
+func newScreen(output xw.Output, settings config.Output, snapshot *xgraphics.Image, scale float64) *screen {
+	// upload the BGRX pixels as they came from the X server
+	size := snapshot.Bounds().Size()
+	screenTex, err := gfx.NewTextureFromPixels(snapshot.Pix, size.X, size.Y, gl.BGRA, gl.CLAMP_TO_EDGE, gl.CLAMP_TO_EDGE)
+	if err != nil {
+		panic(err)
+	}
+	screenTex.SetOpaque()
+	fbo := gfx.MustFramebuffer(output.Rect.Dx(), output.Rect.Dy())
+	return &screen{
+		output:     output,
+		settings:   settings,
+		screenshot: gfx.NewMesh(gvd.PlaneVertices, gvd.PlaneIndices, []*gfx.Texture{screenTex}),
+		fbo:        fbo,
+		params:     map[string][]float32{},
+		scale:      scale,
+	}
+}
+
+// viewport returns the area of the output in GL window coordinates,
+// bounds is the area of the window in root window coordinates
+func (s *screen) viewport(bounds image.Rectangle) image.Rectangle {
+	r := s.output.Rect
+	return image.Rect(r.Min.X-bounds.Min.X, bounds.Max.Y-r.Max.Y, r.Max.X-bounds.Min.X, bounds.Max.Y-r.Min.Y)
+}
+
+// frame is the input of the effects for a single frame
+type frame struct {
+	// time is the time since gllock started in seconds
+	time float64
+	// index counts the frames, starting at 0
+	index int
+	date  time.Time
+	// seed varies the randomness of the effects between runs
+	seed    float32
+	pointer image.Point
+	auth    authState
+}
+
+// draw renders the screenshot with the effect program,
+// followed by overlay and text
+func (s *screen) draw(bounds image.Rectangle, planeProg *gfx.Program, f frame) {
+	r := s.output.Rect
+	if s.pipeline != nil {
+		// render to framebuffer
+		s.fbo.Bind()
+		setViewport(image.Rect(0, 0, r.Dx(), r.Dy()))
+		planeProg.Use()
+		s.screenshot.Draw(planeProg)
+		s.fbo.Unbind()
+
+		// pointer position in pixels, relative to the bottom left of the output
+		mouse := image.Pt(f.pointer.X-r.Min.X, r.Max.Y-f.pointer.Y)
+		s.pipeline.Run(s.viewport(bounds), func(prog *gfx.Program) {
+			// programs are shared by outputs with different params
+			prog.SetParams(s.params)
+			gl.Uniform1f(prog.GetUniformLocation("time"), float32(f.time))
+			gl.Uniform2i(prog.GetUniformLocation("resolution"), int32(r.Dx()), int32(r.Dy()))
+			gl.Uniform2f(prog.GetUniformLocation("mouse"), float32(mouse.X), float32(mouse.Y))
+			gl.Uniform1f(prog.GetUniformLocation("seed"), f.seed)
+			gl.Uniform1i(prog.GetUniformLocation("attempts"), int32(f.auth.attempts))
+			gl.Uniform1f(prog.GetUniformLocation("sinceKey"), since(f.auth.lastKey, f.date))
+			gl.Uniform1f(prog.GetUniformLocation("sinceFailure"), since(f.auth.lastFailure, f.date))
+			gl.Uniform1f(prog.GetUniformLocation("sinceUnlock"), since(f.auth.unlocked, f.date))
+			gfx.SetShadertoyUniforms(prog, gfx.ShadertoyInput{
+				Time:       f.time,
+				Frame:      f.index,
+				Resolution: r.Size(),
+				Mouse:      mouse,
+				Date:       f.date,
+			})
+		})
+	}
+	if s.overlay != nil {
+		s.overlay.draw(planeProg, s.viewport(bounds), s.settings.Position, s.scale)
+	}
+	if s.text != nil {
+		// the bitmap font is tiny, draw it at twice its size
+		s.text.draw(planeProg, s.viewport(bounds), s.settings.TextPosition, 2*s.scale)
+	}
+}
+
+// resize adapts the framebuffer to a new mode of the output.
+// The screenshot is stretched to the new size.
+func (s *screen) resize(output xw.Output) {
+	s.output = output
+	s.fbo.Resize(output.Rect.Dx(), output.Rect.Dy())
+	if s.pipeline != nil {
+		s.pipeline.Resize(output.Rect.Dx(), output.Rect.Dy())
+	}
+}
+
+// uses reports whether a pass of the pipeline runs prog
+func (s *screen) uses(prog *gfx.Program) bool {
+	if s.pipeline == nil {
+		return false
+	}
+	for _, pass := range s.pipeline.Passes() {
+		if pass.Program == prog {
+			return true
+		}
+	}
+	return false
+}
+
+// checkParams drops the parameter overrides that the passes no longer
+// accept, e.g. after an effect changed the type of a parameter.
+// It returns a message for every dropped override.
+func (s *screen) checkParams() []string {
+	var dropped []string
+	for param, values := range s.params {
+		found := false
+		var err error
+		for _, pass := range s.pipeline.Passes() {
+			if p := pass.Program.Param(param); p != nil {
+				found = true
+				if err == nil {
+					err = p.Check(values)
+				}
+			}
+		}
+		if !found {
+			err = fmt.Errorf("param %s does not exist anymore", param)
+		}
+		if err != nil {
+			delete(s.params, param)
+			dropped = append(dropped, fmt.Sprintf("output %s: dropped override, %s", s.output.Name, err))
+		}
+	}
+	return dropped
+}
+
+// destroy releases the framebuffers
+func (s *screen) destroy() {
+	s.fbo.Destroy()
+	if s.pipeline != nil {
+		s.pipeline.Destroy()
+	}
+}
+
+// sprite is a texture that is drawn with a scale factor
+type sprite struct {
+	tex   *gfx.Texture
+	plane *gfx.Mesh
+}
+
+func newSprite(tex *gfx.Texture) *sprite {
+	return &sprite{
+		tex:   tex,
+		plane: gfx.NewMesh(gvd.PlaneVertices, gvd.PlaneIndices, []*gfx.Texture{tex}),
+	}
+}
+
+// draw renders the sprite at position inside of area
+func (sp *sprite) draw(prog *gfx.Program, area image.Rectangle, position string, scale float64) {
+	prog.Use()
+	width := int(float64(sp.tex.Width) * scale)
+	height := int(float64(sp.tex.Height) * scale)
+	setViewport(place(area, width, height, position, int(overlayMargin*scale)))
+	sp.plane.Draw(prog)
+}
+
+// place returns a rectangle of the given size inside of area.
+// area is in GL window coordinates, so top is at the maximum y.
+func place(area image.Rectangle, width, height int, position string, margin int) image.Rectangle {
+	x := area.Min.X + (area.Dx()-width)/2
+	y := area.Min.Y + (area.Dy()-height)/2
+	switch position {
+	case "top", "top-left", "top-right":
+		y = area.Max.Y - height - margin
+	case "bottom", "bottom-left", "bottom-right":
+		y = area.Min.Y + margin
+	}
+	switch position {
+	case "left", "top-left", "bottom-left":
+		x = area.Min.X + margin
+	case "right", "top-right", "bottom-right":
+		x = area.Max.X - width - margin
+	}
+	return image.Rect(x, y, x+width, y+height)
+}
+
+func setViewport(r image.Rectangle) {
+	gl.Viewport(int32(r.Min.X), int32(r.Min.Y), int32(r.Dx()), int32(r.Dy()))
+}
+
+// outputBounds returns the smallest rectangle containing all outputs
+func outputBounds(outputs []xw.Output) image.Rectangle {
+	var bounds image.Rectangle
+	for _, o := range outputs {
+		bounds = bounds.Union(o.Rect)
+	}
+	return bounds
+}
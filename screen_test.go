@@ -0,0 +1,34 @@
+package main
+
+import (
+	"image"
+	"testing"
+)
+
+func TestPlace(t *testing.T) {
+	// area is in GL window coordinates, top is at the maximum y
+	area := image.Rect(100, 0, 1100, 500)
+	for _, tc := range []struct {
+		position string
+		want     image.Rectangle
+	}{
+		{"center", image.Rect(550, 200, 650, 300)},
+		{"top", image.Rect(550, 390, 650, 490)},
+		{"bottom", image.Rect(550, 10, 650, 110)},
+		{"left", image.Rect(110, 200, 210, 300)},
+		{"right", image.Rect(990, 200, 1090, 300)},
+		{"top-left", image.Rect(110, 390, 210, 490)},
+		{"top-right", image.Rect(990, 390, 1090, 490)},
+		{"bottom-left", image.Rect(110, 10, 210, 110)},
+		{"bottom-right", image.Rect(990, 10, 1090, 110)},
+	} {
+		if got := place(area, 100, 100, tc.position, 10); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.position, got, tc.want)
+		}
+	}
+
+	// an overlay larger than the area stays centered
+	if got, want := place(image.Rect(0, 0, 100, 100), 200, 200, "center", 0), image.Rect(-50, -50, 150, 150); got != want {
+		t.Errorf("larger than the area: got %v, want %v", got, want)
+	}
+}
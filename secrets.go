@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/moolen/gllock/agent"
+	"github.com/moolen/gllock/audit"
+	"github.com/moolen/gllock/config"
+	"github.com/moolen/gllock/xw"
+	log "github.com/sirupsen/logrus"
+)
+
+// clearSecrets runs the opt-in steps that protect secrets
+// outside of the screen and records each of them in the audit log
+func clearSecrets(cfg config.Secrets, x *xw.XW, events *audit.Log) {
+	step := func(enabled bool, t audit.Type, fn func() error) {
+		if !enabled {
+			return
+		}
+		if err := fn(); err != nil {
+			log.Warnf("%s: %s", t, err)
+			events.Fail(t, err)
+			return
+		}
+		events.Emit(t, 0)
+	}
+	step(cfg.Selections, audit.SelectionsCleared, x.ClearSelections)
+	step(cfg.SSHAgent, audit.SSHAgentFlushed, func() error {
+		return agent.FlushSSH(cfg.SSHAgentSocket)
+	})
+	step(cfg.GPGAgent, audit.GPGAgentFlushed, func() error {
+		return agent.FlushGPG(cfg.GPGAgentSocket)
+	})
+}
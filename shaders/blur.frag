@@ -0,0 +1,30 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform ivec2 resolution;
+
+// radius of the blur in pixels
+uniform float radius; // @param default=12 min=1 max=64
+
+// taps in every direction, the kernel has (2 * samples + 1)^2 taps
+const int samples = 6;
+
+void main()
+{
+    vec2 texel = radius / float(samples) / vec2(resolution);
+    float sigma = float(samples) / 2.0;
+    vec3 sum = vec3(0.0);
+    float weights = 0.0;
+    for (int x = -samples; x <= samples; x++) {
+        for (int y = -samples; y <= samples; y++) {
+            float w = exp(-float(x * x + y * y) / (2.0 * sigma * sigma));
+            sum += texture(texture0, TexCoord + vec2(x, y) * texel).rgb * w;
+            weights += w;
+        }
+    }
+    color = vec4(sum / weights, 1.0);
+}
@@ -0,0 +1,41 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform float time;
+uniform ivec2 resolution;
+
+// lower values bend the screen more
+uniform float curvature; // @param default=4 min=1 max=20
+// darkness between the scanlines
+uniform float scanlines; // @param default=0.35 min=0 max=1
+// darkness of the corners
+uniform float vignette; // @param default=0.3 min=0 max=2
+
+vec2 curve(vec2 uv)
+{
+    uv = uv * 2.0 - 1.0;
+    vec2 offset = abs(uv.yx) / curvature;
+    uv = uv + uv * offset * offset;
+    return uv * 0.5 + 0.5;
+}
+
+void main()
+{
+    vec2 uv = curve(TexCoord);
+    if (uv.x < 0.0 || uv.x > 1.0 || uv.y < 0.0 || uv.y > 1.0) {
+        color = vec4(0.0, 0.0, 0.0, 1.0);
+        return;
+    }
+    vec3 c = texture(texture0, uv).rgb;
+    float line = sin(uv.y * float(resolution.y) * 3.14159);
+    c *= 1.0 - scanlines * (0.5 - 0.5 * line);
+    // a slow bright band rolls over the screen
+    c *= 1.0 + 0.05 * sin(uv.y * 8.0 - time * 2.0);
+    vec2 v = uv * (1.0 - uv);
+    c *= pow(v.x * v.y * 16.0, vignette);
+    color = vec4(c, 1.0);
+}
@@ -10,6 +10,21 @@ out vec4 color;
 uniform sampler2D texture0;
 layout(location = 1) uniform float time;
 layout(location = 2) uniform ivec2 resolution;
+uniform float sinceFailure;
+uniform float seed;
+
+// maximum strength of the glitch
+uniform float strength; // @param default=0.3 min=0 max=1
+// shake in pixels at full strength
+uniform float shake; // @param default=40 min=0 max=200
+// threshold below which noise turns into displaced blocks
+uniform float blockNoise; // @param default=0.12 min=0 max=1
+// brightness of the white noise
+uniform float whiteNoise; // @param default=0.8 min=0 max=2
+// brightness of the horizontal stripes
+uniform float stripeNoise; // @param default=0.45 min=0 max=2
+// density of the horizontal stripes
+uniform float stripes; // @param default=1200 min=0 max=4000
 
 //
 // Description : Array and textureless GLSL 2D/3D/4D simplex
@@ -117,42 +132,44 @@ float random(vec2 c){
     return fract(sin(dot(c.xy ,vec2(12.9898,78.233))) * 43758.5453);
 }
 
-float interval = 3.0;
-
 void main(){
-    float strength = 0.3 * snoise3(vec3(0.0, TexCoord.y * TexCoord.x, time * 4.0));
-    vec2 shake = vec2(strength * 40.0 + 0.5) * vec2(
-        random(vec2(time)) * 2.0 - 1.0,
-        random(vec2(time * 2.0)) * 2.0 - 1.0
+    // the seed moves every run to a different place in the noise
+    float t = time + seed * 64.0;
+    // the glitch flares up after a failed attempt
+    float flare = sinceFailure < 0.0 ? 1.0 : 1.0 + 2.0 * exp(-sinceFailure * 3.0);
+    float glitch = strength * flare * snoise3(vec3(0.0, TexCoord.y * TexCoord.x, t * 4.0));
+    vec2 shakeUv = vec2(glitch * shake + 0.5) * vec2(
+        random(vec2(t)) * 2.0 - 1.0,
+        random(vec2(t * 2.0)) * 2.0 - 1.0
     ) / resolution;
 
     float y = TexCoord.y * resolution.y;
     float rgbWave = (
-        snoise3(vec3(0.0, y * 0.01, time * 400.0)) * (2.0 + strength * 32.0)
-        * snoise3(vec3(0.0, y * 0.02, time * 200.0)) * (1.0 + strength * 4.0)
-        + step(0.9995, sin(y * 0.005 + time * 1.6)) * 12.0
-        + step(0.9999, sin(y * 0.005 + time * 2.0)) * -18.0
+        snoise3(vec3(0.0, y * 0.01, t * 400.0)) * (2.0 + glitch * 32.0)
+        * snoise3(vec3(0.0, y * 0.02, t * 200.0)) * (1.0 + glitch * 4.0)
+        + step(0.9995, sin(y * 0.005 + t * 1.6)) * 12.0
+        + step(0.9999, sin(y * 0.005 + t * 2.0)) * -18.0
         ) / resolution.x;
-    float rgbDiff = (6.0 + sin(time * 500.0 + TexCoord.y * 40.0) * (0.2)) / resolution.x * 2;
+    float rgbDiff = (6.0 + sin(t * 500.0 + TexCoord.y * 40.0) * (0.2)) / resolution.x * 2;
     float rgbUvX = TexCoord.x + rgbWave;
-    float r = texture2D(texture0, vec2(rgbUvX + rgbDiff, TexCoord.y) + shake).r;
-    float g = texture2D(texture0, vec2(rgbUvX, TexCoord.y) + shake).g;
-    float b = texture2D(texture0, vec2(rgbUvX - rgbDiff, TexCoord.y) + shake).b;
+    float r = texture2D(texture0, vec2(rgbUvX + rgbDiff, TexCoord.y) + shakeUv).r;
+    float g = texture2D(texture0, vec2(rgbUvX, TexCoord.y) + shakeUv).g;
+    float b = texture2D(texture0, vec2(rgbUvX - rgbDiff, TexCoord.y) + shakeUv).b;
 
 
-    float bnTime = floor(time * 20.0) * 20.0;
-    float noiseX = step((snoise3(vec3(0.0, TexCoord.x * 3.0, bnTime)) + 1.0) / 2.0, 0.12 + strength * 0.3);
-    float noiseY = step((snoise3(vec3(0.0, TexCoord.y * 3.0, bnTime)) + 1.0) / 2.0, 0.12 + strength * 2.3);
+    float bnTime = floor(t * 20.0) * 20.0;
+    float noiseX = step((snoise3(vec3(0.0, TexCoord.x * 3.0, bnTime)) + 1.0) / 2.0, blockNoise + glitch * 0.3);
+    float noiseY = step((snoise3(vec3(0.0, TexCoord.y * 3.0, bnTime)) + 1.0) / 2.0, blockNoise + glitch * 2.3);
     float bnMask = noiseX * noiseY;
     float bnUvX = TexCoord.x + sin(bnTime) * 0.2 + rgbWave;
     float bnUvY = TexCoord.y + sin(bnTime) * 0.2 + rgbWave;
     float bnR = texture2D(texture0, vec2(bnUvX + rgbDiff, TexCoord.y)).r * bnMask;
     float bnG = texture2D(texture0, vec2(bnUvX, TexCoord.y)).g * bnMask;
     float bnB = texture2D(texture0, vec2(bnUvX - rgbDiff, TexCoord.y)).b * bnMask;
-    vec4 blockNoise = vec4(bnR, bnG, bnB, 1.0);
+    vec4 blockNoiseColor = vec4(bnR, bnG, bnB, 1.0);
 
-    float whiteNoise = 0.8 * (random(TexCoord + mod(time, 10.0)) * 2.0 - 1.0) * (0.15 + strength * 0.15);
-    float stripeNoise = 0.45 * (sin(TexCoord.y * 1200.0) + 1.0) / 2.0 * (0.15 + strength * 0.2);
+    float white = whiteNoise * (random(TexCoord + mod(t, 10.0)) * 2.0 - 1.0) * (0.15 + glitch * 0.15);
+    float stripe = stripeNoise * (sin(TexCoord.y * stripes) + 1.0) / 2.0 * (0.15 + glitch * 0.2);
 
-    gl_FragColor = vec4(r, g, b, 1.0) * (1.0 - bnMask) + (whiteNoise + blockNoise + stripeNoise);
+    gl_FragColor = vec4(r, g, b, 1.0) * (1.0 - bnMask) + (white + blockNoiseColor + stripe);
 }
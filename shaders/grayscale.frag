@@ -0,0 +1,17 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+
+// 0 keeps the brightness, 1 is black
+uniform float darken; // @param default=0.4 min=0 max=1
+
+void main()
+{
+    vec3 c = texture(texture0, TexCoord).rgb;
+    float luma = dot(c, vec3(0.2126, 0.7152, 0.0722));
+    color = vec4(vec3(luma * (1.0 - darken)), 1.0);
+}
@@ -0,0 +1,50 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform float time;
+uniform ivec2 resolution;
+uniform float seed;
+
+// size of a glyph in pixels
+uniform float cell; // @param default=14 min=4 max=64
+// speed of the rain
+uniform float speed; // @param default=1 min=0 max=10
+// how much the screenshot is darkened
+uniform float dim; // @param default=0.6 min=0 max=1
+
+// length of a trail in glyphs
+const float trail = 16.0;
+
+float random(vec2 c)
+{
+    return fract(sin(dot(c, vec2(12.9898, 78.233))) * 43758.5453);
+}
+
+void main()
+{
+    vec2 px = TexCoord * vec2(resolution);
+    vec2 id = floor(px / cell);
+    vec2 inCell = fract(px / cell);
+    float column = random(vec2(id.x, seed));
+    float rows = float(resolution.y) / cell;
+
+    // the head of every column falls from the top with its own speed
+    float head = rows - mod(time * speed * (4.0 + column * 8.0) + column * rows * 3.0, rows * 1.5);
+    float dist = id.y - head;
+    float intensity = dist >= 0.0 && dist < trail ? 1.0 - dist / trail : 0.0;
+
+    // glyphs are random 3x5 bit patterns that change over time
+    vec2 bit = floor(inCell * vec2(3.0, 5.0));
+    float glyph = step(0.5, random(id * 7.0 + bit + floor(time * speed * 2.0 + column * 10.0)));
+    // keep a gap between the glyphs
+    glyph *= step(0.15, inCell.x) * step(inCell.x, 0.85) * step(0.1, inCell.y) * step(inCell.y, 0.9);
+
+    vec3 c = texture(texture0, TexCoord).rgb * (1.0 - dim);
+    c += vec3(0.1, 1.0, 0.3) * glyph * intensity;
+    c += vec3(0.8) * glyph * step(dist, 1.0) * step(0.0, dist);
+    color = vec4(c, 1.0);
+}
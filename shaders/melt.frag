@@ -0,0 +1,33 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform float time;
+uniform ivec2 resolution;
+uniform float seed;
+
+// fraction of the output a column falls per second
+uniform float speed; // @param default=0.02 min=0 max=1
+// width of a column in pixels
+uniform float width; // @param default=8 min=1 max=128
+
+float random(vec2 c)
+{
+    return fract(sin(dot(c, vec2(12.9898, 78.233))) * 43758.5453);
+}
+
+void main()
+{
+    float column = floor(TexCoord.x * float(resolution.x) / width);
+    // neighbouring columns fall at slightly different speeds
+    float offset = time * speed * (0.5 + random(vec2(column, seed)));
+    vec2 uv = vec2(TexCoord.x, TexCoord.y + offset);
+    if (uv.y > 1.0) {
+        color = vec4(0.0, 0.0, 0.0, 1.0);
+        return;
+    }
+    color = vec4(texture(texture0, uv).rgb, 1.0);
+}
@@ -0,0 +1,18 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform ivec2 resolution;
+
+// size of a block in pixels
+uniform float size; // @param default=16 min=2 max=256
+
+void main()
+{
+    vec2 block = size / vec2(resolution);
+    vec2 uv = (floor(TexCoord / block) + 0.5) * block;
+    color = vec4(texture(texture0, uv).rgb, 1.0);
+}
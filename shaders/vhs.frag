@@ -0,0 +1,42 @@
+#version 410 core
+
+in vec2 TexCoord;
+
+out vec4 color;
+
+uniform sampler2D texture0;
+uniform float time;
+uniform ivec2 resolution;
+uniform float seed;
+
+// strength of the tracking distortion
+uniform float tracking; // @param default=0.6 min=0 max=2
+// strength of the static
+uniform float noise; // @param default=0.15 min=0 max=1
+
+float random(vec2 c)
+{
+    return fract(sin(dot(c, vec2(12.9898, 78.233))) * 43758.5453);
+}
+
+void main()
+{
+    vec2 uv = TexCoord;
+    float line = floor(uv.y * float(resolution.y));
+
+    // a band of bad tracking rolls down the screen
+    float band = smoothstep(0.05, 0.0, abs(uv.y - (1.0 - fract(time * 0.1))));
+    uv.x += band * tracking * 0.05 * (random(vec2(line, time)) - 0.5);
+    // every line jitters a little
+    uv.x += (random(vec2(line, floor(time * 24.0))) - 0.5) * 0.002 * tracking;
+
+    float shift = 2.0 / float(resolution.x);
+    vec3 c;
+    c.r = texture(texture0, uv + vec2(shift, 0.0)).r;
+    c.g = texture(texture0, uv).g;
+    c.b = texture(texture0, uv - vec2(shift, 0.0)).b;
+
+    c += (random(uv * float(resolution.y) + time + seed) - 0.5) * noise;
+    c += band * 0.2 * random(vec2(uv.x * 500.0, time));
+    color = vec4(c, 1.0);
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	summaryNone   = "none"
+	summaryStderr = "stderr"
+	summaryNotify = "notify"
+	summaryFrame  = "frame"
+)
+
+// summaryFrameDuration is how long the summary frame stays on screen
+const summaryFrameDuration = 3 * time.Second
+
+// summaryLines describes the failed attempts of a lock session.
+// It returns nil if there were none.
+func summaryLines(failures []time.Time) []string {
+	if len(failures) == 0 {
+		return nil
+	}
+	lines := []string{fmt.Sprintf("%d failed unlock attempt(s) while locked:", len(failures))}
+	for _, t := range failures {
+		lines = append(lines, "  "+t.Format("2006-01-02 15:04:05"))
+	}
+	return lines
+}
+
+// showSummary reports the failed attempts on stderr or as desktop notification.
+// The frame mode is handled by the render loop.
+func showSummary(mode string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	switch mode {
+	case summaryStderr:
+		fmt.Fprintln(os.Stderr, strings.Join(lines, "\n"))
+	case summaryNotify:
+		cmd := exec.Command("notify-send", "--app-name=gllock", lines[0], strings.Join(lines[1:], "\n"))
+		if err := cmd.Start(); err != nil {
+			log.Warnf("could not send notification: %s", err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package xw
+
+import (
+	"fmt"
+	"image"
+	"time"
+
+	"github.com/BurntSushi/xgb/shm"
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xgraphics"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sys/unix"
+)
+
+// Capture reads the contents of the root window inside r,
+// e.g. the area of a single output. It uses MIT-SHM if the server
+// supports it and falls back to GetImage otherwise.
+// The pixels are in BGRX order, the alpha channel is undefined.
+func (x *XW) Capture(r image.Rectangle) (*xgraphics.Image, error) {
+	start := time.Now()
+	defer func() {
+		log.Debugf("captured %v in %s", r, time.Since(start))
+	}()
+	if x.shm {
+		img, err := x.captureShm(r)
+		if err == nil {
+			return img, nil
+		}
+		log.Debugf("MIT-SHM capture failed, falling back to GetImage: %s", err)
+	}
+	return x.getImage(xproto.Drawable(x.Xu.RootWin()), r)
+}
+
+// captureShm lets the server write the image into a shared memory segment
+func (x *XW) captureShm(r image.Rectangle) (*xgraphics.Image, error) {
+	size := r.Dx() * r.Dy() * 4
+	id, err := unix.SysvShmGet(unix.IPC_PRIVATE, size, unix.IPC_CREAT|0600)
+	if err != nil {
+		return nil, err
+	}
+	data, err := unix.SysvShmAttach(id, 0, 0)
+	if err != nil {
+		unix.SysvShmCtl(id, unix.IPC_RMID, nil)
+		return nil, err
+	}
+	defer unix.SysvShmDetach(data)
+
+	seg, err := shm.NewSegId(x.X)
+	if err != nil {
+		unix.SysvShmCtl(id, unix.IPC_RMID, nil)
+		return nil, err
+	}
+	err = shm.AttachChecked(x.X, seg, uint32(id), false).Check()
+	// the segment is removed once both sides detached
+	unix.SysvShmCtl(id, unix.IPC_RMID, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer shm.Detach(x.X, seg)
+
+	reply, err := shm.GetImage(x.X, xproto.Drawable(x.Xu.RootWin()),
+		int16(r.Min.X), int16(r.Min.Y), uint16(r.Dx()), uint16(r.Dy()), 0xffffffff,
+		xproto.ImageFormatZPixmap, seg, 0).Reply()
+	if err != nil {
+		return nil, err
+	}
+	if int(reply.Size) != size {
+		return nil, fmt.Errorf("unsupported image format: depth %d, %d bytes", reply.Depth, reply.Size)
+	}
+	pix := make([]byte, size)
+	copy(pix, data)
+	return newImage(x, pix, r), nil
+}
+
+// getImage transfers the contents of r in drawable over the X connection
+func (x *XW) getImage(drawable xproto.Drawable, r image.Rectangle) (*xgraphics.Image, error) {
+	reply, err := xproto.GetImage(x.X, xproto.ImageFormatZPixmap, drawable,
+		int16(r.Min.X), int16(r.Min.Y), uint16(r.Dx()), uint16(r.Dy()), 0xffffffff).Reply()
+	if err != nil {
+		return nil, err
+	}
+	if len(reply.Data) != r.Dx()*r.Dy()*4 {
+		return nil, fmt.Errorf("unsupported image format: depth %d, %d bytes", reply.Depth, len(reply.Data))
+	}
+	return newImage(x, reply.Data, r), nil
+}
+
+func newImage(x *XW, pix []byte, r image.Rectangle) *xgraphics.Image {
+	return &xgraphics.Image{
+		X:      x.Xu,
+		Pix:    pix,
+		Stride: 4 * r.Dx(),
+		Rect:   image.Rect(0, 0, r.Dx(), r.Dy()),
+	}
+}
@@ -0,0 +1,67 @@
+package xw
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/ewmh"
+	"github.com/BurntSushi/xgbutil/icccm"
+)
+
+// Client is a top-level window managed by the window manager
+type Client struct {
+	Window xproto.Window
+	// Title is _NET_WM_NAME or WM_NAME
+	Title string
+	// Instance and Class are the two parts of WM_CLASS
+	Instance string
+	Class    string
+	// Rect is the geometry of the window in root window coordinates,
+	// without the decorations of the window manager
+	Rect image.Rectangle
+}
+
+// Clients returns the viewable clients of the EWMH stacking list,
+// from bottom to top. Minimized windows and windows on other
+// desktops are not viewable and left out. If the geometry of any
+// client can't be read an error is returned, so callers never
+// miss a window.
+func (x *XW) Clients() ([]Client, error) {
+	ids, err := ewmh.ClientListStackingGet(x.Xu)
+	if err != nil {
+		return nil, err
+	}
+	var clients []Client
+	for _, id := range ids {
+		attrs, err := xproto.GetWindowAttributes(x.X, id).Reply()
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %s", id, err)
+		}
+		if attrs.MapState != xproto.MapStateViewable {
+			continue
+		}
+		geom, err := xproto.GetGeometry(x.X, xproto.Drawable(id)).Reply()
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %s", id, err)
+		}
+		// the window is most likely reparented into a frame
+		pos, err := xproto.TranslateCoordinates(x.X, id, x.Xu.RootWin(), 0, 0).Reply()
+		if err != nil {
+			return nil, fmt.Errorf("window %d: %s", id, err)
+		}
+		c := Client{
+			Window: id,
+			Rect:   image.Rect(int(pos.DstX), int(pos.DstY), int(pos.DstX)+int(geom.Width), int(pos.DstY)+int(geom.Height)),
+		}
+		c.Title, err = ewmh.WmNameGet(x.Xu, id)
+		if err != nil || len(c.Title) == 0 {
+			c.Title, _ = icccm.WmNameGet(x.Xu, id)
+		}
+		if class, err := icccm.WmClassGet(x.Xu, id); err == nil {
+			c.Instance, c.Class = class.Instance, class.Class
+		}
+		clients = append(clients, c)
+	}
+	return clients, nil
+}
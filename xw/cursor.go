@@ -0,0 +1,212 @@
+package xw
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"strings"
+
+	// decode cursor images
+	_ "image/png"
+
+	"github.com/BurntSushi/xgb/render"
+	"github.com/BurntSushi/xgb/xproto"
+)
+
+const (
+	// CursorHidden hides the pointer while locked
+	CursorHidden = "hidden"
+	// CursorVisible keeps the current pointer
+	CursorVisible = "visible"
+)
+
+// maxPutImage is the maximum payload of a single PutImage request
+const maxPutImage = 1 << 16
+
+// LoadCursor sets the cursor that is shown while the pointer is grabbed.
+// spec is either CursorHidden, CursorVisible, a path to a PNG image,
+// a path to an Xcursor file or a themed cursor like "Adwaita/left_ptr".
+func (x *XW) LoadCursor(spec string) error {
+	var cursor xproto.Cursor
+	var err error
+	switch {
+	case spec == "" || spec == CursorHidden:
+		cursor, err = x.invisibleCursor()
+	case spec == CursorVisible:
+		cursor = xproto.CursorNone
+	case strings.HasSuffix(strings.ToLower(spec), ".png"):
+		cursor, err = x.pngCursor(spec)
+	default:
+		path := spec
+		if _, statErr := os.Stat(path); statErr != nil {
+			path, err = findXcursor(spec)
+			if err != nil {
+				return err
+			}
+		}
+		img, hot, readErr := readXcursor(path)
+		if readErr != nil {
+			return readErr
+		}
+		cursor, err = x.imageCursor(img, hot)
+	}
+	if err != nil {
+		return err
+	}
+	// the server keeps the cursor of an active grab until the grab ends
+	if x.cursor != xproto.CursorNone {
+		xproto.FreeCursor(x.X, x.cursor)
+	}
+	x.cursor = cursor
+	return nil
+}
+
+// invisibleCursor creates a cursor from an empty 1x1 bitmap
+func (x *XW) invisibleCursor() (xproto.Cursor, error) {
+	pix, err := xproto.NewPixmapId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreatePixmapChecked(x.X, 1, pix, xproto.Drawable(x.Xu.RootWin()), 1, 1).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreePixmap(x.X, pix)
+
+	gc, err := xproto.NewGcontextId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateGCChecked(x.X, gc, xproto.Drawable(pix), xproto.GcForeground, []uint32{0}).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreeGC(x.X, gc)
+	xproto.PolyFillRectangle(x.X, xproto.Drawable(pix), gc, []xproto.Rectangle{{X: 0, Y: 0, Width: 1, Height: 1}})
+
+	cursor, err := xproto.NewCursorId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateCursorChecked(x.X, cursor, pix, pix, 0, 0, 0, 0, 0, 0, 0, 0).Check()
+	if err != nil {
+		return 0, err
+	}
+	return cursor, nil
+}
+
+// pngCursor creates a cursor from a PNG image with the hotspot in its center
+func (x *XW) pngCursor(path string) (xproto.Cursor, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return 0, err
+	}
+	size := img.Bounds().Size()
+	return x.imageCursor(img, image.Pt(size.X/2, size.Y/2))
+}
+
+// imageCursor creates an ARGB cursor using the RENDER extension
+func (x *XW) imageCursor(img image.Image, hot image.Point) (xproto.Cursor, error) {
+	if err := render.Init(x.X); err != nil {
+		return 0, err
+	}
+	format, err := x.argb32Format()
+	if err != nil {
+		return 0, err
+	}
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	pix, err := xproto.NewPixmapId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreatePixmapChecked(x.X, 32, pix, xproto.Drawable(x.Xu.RootWin()), uint16(width), uint16(height)).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreePixmap(x.X, pix)
+
+	gc, err := xproto.NewGcontextId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = xproto.CreateGCChecked(x.X, gc, xproto.Drawable(pix), 0, nil).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer xproto.FreeGC(x.X, gc)
+
+	// premultiplied BGRA, the byte order of ARGB32 on little endian servers
+	data := make([]byte, width*height*4)
+	for py := 0; py < height; py++ {
+		for px := 0; px < width; px++ {
+			r, g, b, a := img.At(bounds.Min.X+px, bounds.Min.Y+py).RGBA()
+			i := (py*width + px) * 4
+			data[i+0] = uint8(b >> 8)
+			data[i+1] = uint8(g >> 8)
+			data[i+2] = uint8(r >> 8)
+			data[i+3] = uint8(a >> 8)
+		}
+	}
+	// upload in chunks of rows to stay below the maximum request size
+	rows := maxPutImage / (width * 4)
+	if rows == 0 {
+		return 0, fmt.Errorf("cursor image too wide: %d", width)
+	}
+	for y := 0; y < height; y += rows {
+		n := rows
+		if y+n > height {
+			n = height - y
+		}
+		err = xproto.PutImageChecked(x.X, xproto.ImageFormatZPixmap, xproto.Drawable(pix), gc,
+			uint16(width), uint16(n), 0, int16(y), 0, 32, data[y*width*4:(y+n)*width*4]).Check()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	pic, err := render.NewPictureId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = render.CreatePictureChecked(x.X, pic, xproto.Drawable(pix), format, 0, nil).Check()
+	if err != nil {
+		return 0, err
+	}
+	defer render.FreePicture(x.X, pic)
+
+	cursor, err := xproto.NewCursorId(x.X)
+	if err != nil {
+		return 0, err
+	}
+	err = render.CreateCursorChecked(x.X, cursor, pic, uint16(hot.X), uint16(hot.Y)).Check()
+	if err != nil {
+		return 0, err
+	}
+	return cursor, nil
+}
+
+// argb32Format finds the standard 32 bit ARGB picture format
+func (x *XW) argb32Format() (render.Pictformat, error) {
+	formats, err := render.QueryPictFormats(x.X).Reply()
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range formats.Formats {
+		d := f.Direct
+		if f.Type == render.PictTypeDirect && f.Depth == 32 &&
+			d.AlphaShift == 24 && d.AlphaMask == 0xff &&
+			d.RedShift == 16 && d.RedMask == 0xff &&
+			d.GreenShift == 8 && d.GreenMask == 0xff &&
+			d.BlueShift == 0 && d.BlueMask == 0xff {
+			return f.Id, nil
+		}
+	}
+	return 0, fmt.Errorf("no ARGB32 picture format")
+}
@@ -0,0 +1,29 @@
+package xw
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/xgbutil/xprop"
+)
+
+// XftDPI returns the Xft.dpi X resource or 0 if it is not set.
+// It is the resolution the user asked applications to render at.
+func (x *XW) XftDPI() float64 {
+	resources, err := xprop.PropValStr(xprop.GetProperty(x.Xu, x.Xu.RootWin(), "RESOURCE_MANAGER"))
+	if err != nil {
+		return 0
+	}
+	for _, line := range strings.Split(resources, "\n") {
+		kv := strings.SplitN(line, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) != "Xft.dpi" {
+			continue
+		}
+		dpi, err := strconv.ParseFloat(strings.TrimSpace(kv[1]), 64)
+		if err != nil {
+			return 0
+		}
+		return dpi
+	}
+	return 0
+}
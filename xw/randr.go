@@ -0,0 +1,229 @@
+package xw
+
+import (
+	"image"
+	"time"
+
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/xproto"
+	log "github.com/sirupsen/logrus"
+)
+
+// Output is a connected RandR output that is driven by a CRTC
+type Output struct {
+	// Name is the RandR output name, e.g. eDP-1 or HDMI-1
+	Name string
+	// Rect is the CRTC geometry in root window coordinates,
+	// width and height are already swapped for rotated outputs
+	Rect    image.Rectangle
+	Primary bool
+	// MmWidth and MmHeight are the physical size of the unrotated panel
+	MmWidth  int
+	MmHeight int
+	// Rotation is the RandR rotation and reflection of the CRTC
+	Rotation uint16
+}
+
+// DPI returns the physical resolution of the output or 0 if unknown
+func (o Output) DPI() float64 {
+	mmWidth := o.MmWidth
+	if o.Rotation&(randr.RotationRotate90|randr.RotationRotate270) != 0 {
+		// the panel is rotated, so its height is horizontal now
+		mmWidth = o.MmHeight
+	}
+	if mmWidth <= 0 {
+		return 0
+	}
+	return float64(o.Rect.Dx()) / (float64(mmWidth) / 25.4)
+}
+
+// Outputs returns all active outputs
+func (x *XW) Outputs() ([]Output, error) {
+	root := x.Xu.RootWin()
+	res, err := randr.GetScreenResourcesCurrent(x.X, root).Reply()
+	if err != nil {
+		return nil, err
+	}
+	primary, err := randr.GetOutputPrimary(x.X, root).Reply()
+	if err != nil {
+		return nil, err
+	}
+	var outputs []Output
+	for _, id := range res.Outputs {
+		info, err := randr.GetOutputInfo(x.X, id, res.ConfigTimestamp).Reply()
+		if err != nil {
+			return nil, err
+		}
+		if info.Connection != randr.ConnectionConnected || info.Crtc == 0 {
+			continue
+		}
+		crtc, err := randr.GetCrtcInfo(x.X, info.Crtc, res.ConfigTimestamp).Reply()
+		if err != nil {
+			return nil, err
+		}
+		if crtc.Mode == 0 {
+			continue
+		}
+		outputs = append(outputs, Output{
+			Name:     string(info.Name),
+			Rect:     image.Rect(int(crtc.X), int(crtc.Y), int(crtc.X)+int(crtc.Width), int(crtc.Y)+int(crtc.Height)),
+			Primary:  id == primary.Output,
+			MmWidth:  int(info.MmWidth),
+			MmHeight: int(info.MmHeight),
+			Rotation: crtc.Rotation,
+		})
+	}
+	return outputs, nil
+}
+
+// WatchOutputs subscribes to RandR screen, CRTC and output changes.
+// rendered are the outputs that show the lock screen, every other output
+// that shows up while locked is covered immediately.
+// The returned channel receives the current outputs after every change.
+func (x *XW) WatchOutputs(rendered []Output) (<-chan OutputChange, error) {
+	x.mu.Lock()
+	for _, o := range rendered {
+		x.rendered[o.Name] = true
+	}
+	x.outputChanges = make(chan OutputChange, 1)
+	x.mu.Unlock()
+	return x.outputChanges, randr.SelectInputChecked(x.X, x.Xu.RootWin(),
+		randr.NotifyMaskScreenChange|randr.NotifyMaskCrtcChange|randr.NotifyMaskOutputChange).Check()
+}
+
+// OutputChange is sent to the renderer when the outputs changed
+type OutputChange struct {
+	Outputs []Output
+	// Err is set when an output could not be covered,
+	// the renderer has to span its window over it instead
+	Err error
+}
+
+// coverAttempts is how often covering the outputs is tried
+// before the renderer is told to take over
+const coverAttempts = 3
+
+// outputsChanged covers new outputs and notifies the renderer
+func (x *XW) outputsChanged() {
+	outputs, err := x.Outputs()
+	if err != nil {
+		log.Errorf("could not query outputs: %s", err)
+		return
+	}
+	log.Debugf("outputs changed: %v", outputs)
+	for i := 1; i <= coverAttempts; i++ {
+		if err = x.updateCovers(outputs); err == nil {
+			break
+		}
+		log.Warnf("could not cover outputs (attempt %d of %d): %s", i, coverAttempts, err)
+		time.Sleep(50 * time.Millisecond)
+	}
+	// only the latest state matters
+	select {
+	case <-x.outputChanges:
+	default:
+	}
+	x.outputChanges <- OutputChange{Outputs: outputs, Err: err}
+}
+
+// updateCovers covers all outputs that are not rendered,
+// moves existing covers and removes the covers of vanished outputs
+func (x *XW) updateCovers(outputs []Output) error {
+	present := map[string]bool{}
+	for _, o := range outputs {
+		present[o.Name] = true
+		x.mu.Lock()
+		rendered := x.rendered[o.Name]
+		win, covered := x.covers[o.Name]
+		x.mu.Unlock()
+		if rendered {
+			continue
+		}
+		if !covered {
+			if err := x.Cover(o); err != nil {
+				return err
+			}
+			continue
+		}
+		err := xproto.ConfigureWindowChecked(x.X, win,
+			xproto.ConfigWindowX|xproto.ConfigWindowY|xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|xproto.ConfigWindowStackMode,
+			[]uint32{uint32(int32(o.Rect.Min.X)), uint32(int32(o.Rect.Min.Y)), uint32(o.Rect.Dx()), uint32(o.Rect.Dy()), xproto.StackModeAbove}).Check()
+		if err != nil {
+			return err
+		}
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for name, win := range x.covers {
+		if !present[name] {
+			xproto.DestroyWindow(x.X, win)
+			delete(x.covers, name)
+		}
+	}
+	// a rendered output that is plugged in again gets covered
+	for name := range x.rendered {
+		if !present[name] {
+			delete(x.rendered, name)
+		}
+	}
+	return nil
+}
+
+// Cover puts a black override-redirect window on top of the output.
+// Window managers can neither move nor resize it.
+func (x *XW) Cover(o Output) error {
+	win, err := xproto.NewWindowId(x.X)
+	if err != nil {
+		return err
+	}
+	screen := x.Xu.Screen()
+	err = xproto.CreateWindowChecked(x.X, screen.RootDepth, win, screen.Root,
+		int16(o.Rect.Min.X), int16(o.Rect.Min.Y), uint16(o.Rect.Dx()), uint16(o.Rect.Dy()), 0,
+		xproto.WindowClassInputOutput, screen.RootVisual,
+		xproto.CwBackPixel|xproto.CwOverrideRedirect, []uint32{screen.BlackPixel, 1}).Check()
+	if err != nil {
+		return err
+	}
+	err = xproto.MapWindowChecked(x.X, win).Check()
+	if err != nil {
+		xproto.DestroyWindow(x.X, win)
+		return err
+	}
+	log.Debugf("covered output %s at %v", o.Name, o.Rect)
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.covers[o.Name] = win
+	return nil
+}
+
+// Restack raises the fullscreen window and all cover windows
+// above all other windows
+func (x *XW) Restack() error {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	windows := []xproto.Window{x.window}
+	for _, win := range x.covers {
+		windows = append(windows, win)
+	}
+	for _, win := range windows {
+		if win == 0 {
+			continue
+		}
+		err := xproto.ConfigureWindowChecked(x.X, win, xproto.ConfigWindowStackMode,
+			[]uint32{xproto.StackModeAbove}).Check()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DestroyCovers removes all cover windows
+func (x *XW) DestroyCovers() {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	for name, win := range x.covers {
+		xproto.DestroyWindow(x.X, win)
+		delete(x.covers, name)
+	}
+}
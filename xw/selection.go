@@ -0,0 +1,48 @@
+package xw
+
+import (
+	"fmt"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xprop"
+	log "github.com/sirupsen/logrus"
+)
+
+// ClearSelections takes ownership of the CLIPBOARD and PRIMARY selections
+// so their previous owners drop the content, then releases them again.
+func (x *XW) ClearSelections() error {
+	win, err := xproto.NewWindowId(x.X)
+	if err != nil {
+		return err
+	}
+	err = xproto.CreateWindowChecked(x.X, 0, win, x.Xu.RootWin(), 0, 0, 1, 1, 0,
+		xproto.WindowClassInputOnly, 0, 0, nil).Check()
+	if err != nil {
+		return err
+	}
+	defer xproto.DestroyWindow(x.X, win)
+
+	for _, name := range []string{"CLIPBOARD", "PRIMARY"} {
+		atom, err := xprop.Atm(x.Xu, name)
+		if err != nil {
+			return err
+		}
+		err = xproto.SetSelectionOwnerChecked(x.X, win, atom, xproto.TimeCurrentTime).Check()
+		if err != nil {
+			return err
+		}
+		owner, err := xproto.GetSelectionOwner(x.X, atom).Reply()
+		if err != nil {
+			return err
+		}
+		if owner.Owner != win {
+			return fmt.Errorf("could not take ownership of %s", name)
+		}
+		err = xproto.SetSelectionOwnerChecked(x.X, xproto.WindowNone, atom, xproto.TimeCurrentTime).Check()
+		if err != nil {
+			return err
+		}
+		log.Debugf("cleared %s selection", name)
+	}
+	return nil
+}
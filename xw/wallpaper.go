@@ -0,0 +1,27 @@
+package xw
+
+import (
+	"errors"
+	"image"
+
+	"github.com/BurntSushi/xgb/xproto"
+	"github.com/BurntSushi/xgbutil/xgraphics"
+	"github.com/BurntSushi/xgbutil/xprop"
+)
+
+// wallpaperAtoms are set on the root window by wallpaper setters
+// like feh, nitrogen or xsetroot. They hold the pixmap of the wallpaper.
+var wallpaperAtoms = []string{"_XROOTPMAP_ID", "ESETROOT_PMAP_ID"}
+
+// Wallpaper reads the area r of the root window wallpaper.
+// It never touches the contents of other windows.
+func (x *XW) Wallpaper(r image.Rectangle) (*xgraphics.Image, error) {
+	for _, atom := range wallpaperAtoms {
+		pix, err := xprop.PropValNum(xprop.GetProperty(x.Xu, x.Xu.RootWin(), atom))
+		if err != nil || pix == 0 {
+			continue
+		}
+		return x.getImage(xproto.Drawable(pix), r)
+	}
+	return nil, errors.New("no wallpaper set on the root window")
+}
@@ -3,18 +3,17 @@ package xw
 import (
 	"fmt"
 	"image"
+	"sync"
 	"time"
 
 	"github.com/BurntSushi/xgb"
+	"github.com/BurntSushi/xgb/randr"
+	"github.com/BurntSushi/xgb/shm"
 	"github.com/BurntSushi/xgb/xproto"
 	"github.com/BurntSushi/xgbutil"
 	"github.com/BurntSushi/xgbutil/ewmh"
 	"github.com/BurntSushi/xgbutil/icccm"
 	"github.com/BurntSushi/xgbutil/keybind"
-	"github.com/BurntSushi/xgbutil/mousebind"
-	"github.com/BurntSushi/xgbutil/xevent"
-	"github.com/BurntSushi/xgbutil/xgraphics"
-	"github.com/BurntSushi/xgbutil/xwindow"
 	"github.com/moolen/glitchlock/pam"
 	log "github.com/sirupsen/logrus"
 )
@@ -22,6 +21,37 @@ import (
 type XW struct {
 	X  *xgb.Conn
 	Xu *xgbutil.XUtil
+
+	// MaxAttempts is the number of failed attempts after which
+	// all input is ignored for LockoutDuration. Every further
+	// MaxAttempts failures start another lockout, keys pressed while
+	// locked out are dropped and never count. Zero disables the lockout.
+	MaxAttempts     int
+	LockoutDuration time.Duration
+
+	// OnAuthFailure is called after every failed attempt
+	// with the number of failed attempts so far
+	OnAuthFailure func(attempts int)
+	// OnLockout is called when MaxAttempts is reached
+	OnLockout func(attempts int, until time.Time)
+	// OnKeyPress is called for every key that goes to the password prompt
+	OnKeyPress func()
+	// OnGrabLost is called when the keyboard grab was broken.
+	// PasswordMatch tries to re-grab the input afterwards.
+	OnGrabLost func()
+
+	mu       sync.Mutex
+	failures []time.Time
+	bindings map[string]func()
+	cursor   xproto.Cursor
+	pointer  image.Point
+	covers   map[string]xproto.Window
+	window   xproto.Window
+	rendered map[string]bool
+	// shm is set if the server supports MIT-SHM
+	shm bool
+
+	outputChanges chan OutputChange
 }
 
 func New() (*XW, error) {
@@ -33,11 +63,63 @@ func New() (*XW, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &XW{X, Xu}, nil
+	if err := randr.Init(X); err != nil {
+		return nil, err
+	}
+	keybind.Initialize(Xu)
+	x := &XW{
+		X:        X,
+		Xu:       Xu,
+		bindings: map[string]func(){},
+		covers:   map[string]xproto.Window{},
+		rendered: map[string]bool{},
+		shm:      shm.Init(X) == nil,
+	}
+	x.cursor, err = x.invisibleCursor()
+	if err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// Bind registers a key combination like "XF86AudioMute" or "Control-Mod1-s"
+// that runs fn while the screen is locked. Bound keys are never
+// added to the password.
+func (x *XW) Bind(keys string, fn func()) error {
+	if _, _, err := keybind.ParseString(x.Xu, keys); err != nil {
+		return err
+	}
+	x.bindings[keys] = fn
+	return nil
+}
+
+func (x *XW) binding(e xproto.KeyPressEvent) func() {
+	for keys, fn := range x.bindings {
+		if keybind.KeyMatch(x.Xu, keys, e.State, e.Detail) {
+			return fn
+		}
+	}
+	return nil
 }
 
 func (x *XW) GrabInput() error {
-	keybind.Initialize(x.Xu)
+	// focus events tell us when the grab is lost
+	err := xproto.ChangeWindowAttributesChecked(x.X, x.Xu.RootWin(), xproto.CwEventMask,
+		[]uint32{xproto.EventMaskFocusChange}).Check()
+	if err != nil {
+		return err
+	}
+	return x.grab()
+}
+
+// UngrabInput releases keyboard and pointer after the password was accepted
+func (x *XW) UngrabInput() {
+	xproto.UngrabKeyboard(x.X, xproto.TimeCurrentTime)
+	xproto.UngrabPointer(x.X, xproto.TimeCurrentTime)
+	x.X.Sync()
+}
+
+func (x *XW) grab() error {
 	xscreen := xproto.Setup(x.X).DefaultScreen(x.X)
 	grabc := xproto.GrabKeyboard(x.X, false, xscreen.Root, xproto.TimeCurrentTime,
 		xproto.GrabModeAsync, xproto.GrabModeAsync,
@@ -49,8 +131,8 @@ func (x *XW) GrabInput() error {
 	if repk.Status != xproto.GrabStatusSuccess {
 		return fmt.Errorf("could not grab keyboard")
 	}
-	grabp := xproto.GrabPointer(x.X, false, xscreen.Root, (xproto.EventMaskKeyPress|xproto.EventMaskKeyRelease)&0,
-		xproto.GrabModeAsync, xproto.GrabModeAsync, xproto.WindowNone, xproto.CursorNone, xproto.TimeCurrentTime)
+	grabp := xproto.GrabPointer(x.X, false, xscreen.Root, xproto.EventMaskPointerMotion,
+		xproto.GrabModeAsync, xproto.GrabModeAsync, xproto.WindowNone, x.cursor, xproto.TimeCurrentTime)
 	repp, err := grabp.Reply()
 	if err != nil {
 		return fmt.Errorf("error grabbing pointer")
@@ -58,9 +140,27 @@ func (x *XW) GrabInput() error {
 	if repp.Status != xproto.GrabStatusSuccess {
 		return fmt.Errorf("could not grab pointer")
 	}
+	pointer, err := xproto.QueryPointer(x.X, xscreen.Root).Reply()
+	if err != nil {
+		return err
+	}
+	x.setPointer(int(pointer.RootX), int(pointer.RootY))
 	return nil
 }
 
+// Pointer returns the last known pointer position in root window coordinates
+func (x *XW) Pointer() image.Point {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.pointer
+}
+
+func (x *XW) setPointer(px, py int) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.pointer = image.Pt(px, py)
+}
+
 func (x *XW) FindWindow(name string) (xproto.Window, error) {
 	clientids, err := ewmh.ClientListGet(x.Xu)
 	if err != nil {
@@ -81,25 +181,53 @@ func (x *XW) FindWindow(name string) (xproto.Window, error) {
 	return 0, fmt.Errorf("X Window not found")
 }
 
-func (x *XW) Fullscreen(name string) error {
-	win, err := x.FindWindow(name)
+// Fullscreen turns win into an override-redirect window that covers r
+// and raises it above all other windows. The window must not be mapped yet,
+// otherwise a window manager already manages it.
+// It does not depend on a window manager or EWMH.
+func (x *XW) Fullscreen(win xproto.Window, r image.Rectangle) error {
+	err := xproto.ChangeWindowAttributesChecked(x.X, win, xproto.CwOverrideRedirect, []uint32{1}).Check()
 	if err != nil {
 		return err
 	}
-	err = ewmh.WmStateReq(x.Xu, win, ewmh.StateToggle,
-		"_NET_WM_STATE_FULLSCREEN")
+	err = xproto.ConfigureWindowChecked(x.X, win,
+		xproto.ConfigWindowX|xproto.ConfigWindowY|xproto.ConfigWindowWidth|xproto.ConfigWindowHeight|xproto.ConfigWindowStackMode,
+		[]uint32{uint32(int32(r.Min.X)), uint32(int32(r.Min.Y)), uint32(r.Dx()), uint32(r.Dy()), xproto.StackModeAbove}).Check()
 	if err != nil {
 		return err
 	}
+	err = xproto.MapWindowChecked(x.X, win).Check()
+	if err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.window = win
 	return nil
 }
 
+// FailedAttempts returns the time of every failed attempt
+// since PasswordMatch was started
+func (x *XW) FailedAttempts() []time.Time {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return append([]time.Time(nil), x.failures...)
+}
+
+func (x *XW) recordFailure() int {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.failures = append(x.failures, time.Now())
+	return len(x.failures)
+}
+
 func (x *XW) PasswordMatch() <-chan struct{} {
 	lastInput := time.Now()
 	done := make(chan struct{}, 1)
 
 	go func() {
 		var password string
+		var lockedOut time.Time
 		for {
 			ev, err := x.X.WaitForEvent()
 			if ev == nil && err == nil {
@@ -117,17 +245,47 @@ func (x *XW) PasswordMatch() <-chan struct{} {
 				password = ""
 			}
 			switch e := ev.(type) {
+			case randr.ScreenChangeNotifyEvent, randr.NotifyEvent:
+				if x.outputChanges != nil {
+					x.outputsChanged()
+				}
+				continue
+			case xproto.MotionNotifyEvent:
+				x.setPointer(int(e.RootX), int(e.RootY))
+				continue
+			case xproto.FocusOutEvent:
+				if e.Mode != xproto.NotifyModeUngrab {
+					continue
+				}
+				log.Warnf("lost input grab")
+				if x.OnGrabLost != nil {
+					x.OnGrabLost()
+				}
+				if err := x.grab(); err != nil {
+					log.Errorf("could not re-grab input: %s", err)
+				}
 			case xproto.KeyPressEvent:
+				if fn := x.binding(e); fn != nil {
+					log.Debugf("running keybinding %v", e)
+					fn()
+					continue
+				}
 				key := keybind.LookupString(x.Xu, e.State, e.Detail)
-				log.Debugf("keypress: %s %v ", key, e)
 				lastInput = time.Now()
+				if lastInput.Before(lockedOut) {
+					log.Debugf("locked out, ignoring input")
+					password = ""
+					continue
+				}
+				if x.OnKeyPress != nil {
+					x.OnKeyPress()
+				}
 				if len(key) == 1 {
 					password += key
 				}
 				if keybind.KeyMatch(x.Xu, "BackSpace", e.State, e.Detail) && len(password) > 0 {
 					password = password[:len(password)-1]
 				}
-				log.Debugf("current password: %s", password)
 				if keybind.KeyMatch(x.Xu, "Return", e.State, e.Detail) {
 					log.Debugf("...checking password")
 					if pam.AuthenticateCurrentUser(password) {
@@ -135,56 +293,21 @@ func (x *XW) PasswordMatch() <-chan struct{} {
 						return
 					}
 					log.Debugf("password does not match")
+					password = ""
+					attempts := x.recordFailure()
+					if x.OnAuthFailure != nil {
+						x.OnAuthFailure(attempts)
+					}
+					if x.MaxAttempts > 0 && attempts%x.MaxAttempts == 0 {
+						lockedOut = time.Now().Add(x.LockoutDuration)
+						log.Debugf("too many failed attempts, locked out until %s", lockedOut)
+						if x.OnLockout != nil {
+							x.OnLockout(attempts, lockedOut)
+						}
+					}
 				}
 			}
 		}
 	}()
 	return done
 }
-
-func (xw *XW) Overlay(x, y, width, height int) error {
-	log.Debugf("overlay size: %d %d %d %d", x, y, x+width, y+height)
-	rgba := image.NewRGBA(image.Rect(x, y, x+width, y+height))
-	ximg := xgraphics.NewConvert(xw.Xu, rgba)
-	win, err := xwindow.Generate(xw.Xu)
-	if err != nil {
-		return err
-	}
-	win.Create(xw.Xu.RootWin(), x, y, width, height, 0)
-	win.WMGracefulClose(func(w *xwindow.Window) {
-		xevent.Detach(w.X, w.Id)
-		keybind.Detach(w.X, w.Id)
-		mousebind.Detach(w.X, w.Id)
-		w.Destroy()
-	})
-	err = icccm.WmStateSet(xw.Xu, win.Id, &icccm.WmState{
-		State: icccm.StateNormal,
-	})
-	if err != nil {
-		return err
-	}
-	err = ewmh.WmStateSet(xw.Xu, win.Id, []string{"_NET_WM_STATE_FULLSCREEN", "_NET_WM_STATE_ABOVE"})
-	if err != nil {
-		return err
-	}
-	err = icccm.WmNormalHintsSet(xw.Xu, win.Id, &icccm.NormalHints{
-		Flags:     icccm.SizeHintPMinSize | icccm.SizeHintPMaxSize,
-		MinWidth:  uint(width),
-		MinHeight: uint(height),
-		MaxWidth:  uint(width),
-		MaxHeight: uint(height),
-	})
-	if err != nil {
-		return err
-	}
-
-	// Paint our image before mapping.
-	ximg.XSurfaceSet(win.Id)
-	ximg.XDraw()
-	ximg.XPaint(win.Id)
-	win.Map()
-
-	// some WM override this position after mapping
-	win.Move(x, y)
-	return nil
-}
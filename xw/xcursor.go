@@ -0,0 +1,122 @@
+package xw
+
+import (
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	xcursorMagic     = "Xcur"
+	xcursorImageType = 0xfffd0002
+	// xcursorSize is the nominal size we pick from a cursor file
+	xcursorSize = 24
+	// xcursorMaxSize is the largest width and height libXcursor accepts
+	xcursorMaxSize = 0x7fff
+)
+
+// xcursorDirs returns the directories that contain cursor themes
+func xcursorDirs() []string {
+	if path := os.Getenv("XCURSOR_PATH"); path != "" {
+		return filepath.SplitList(path)
+	}
+	home := os.Getenv("HOME")
+	return []string{
+		filepath.Join(home, ".local/share/icons"),
+		filepath.Join(home, ".icons"),
+		"/usr/share/icons",
+		"/usr/share/pixmaps",
+	}
+}
+
+// findXcursor looks up a cursor like "Adwaita/left_ptr" in the cursor theme directories
+func findXcursor(themeCursor string) (string, error) {
+	parts := strings.SplitN(themeCursor, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("cursor must be in the form theme/name: %s", themeCursor)
+	}
+	for _, dir := range xcursorDirs() {
+		path := filepath.Join(dir, parts[0], "cursors", parts[1])
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("cursor %s not found", themeCursor)
+}
+
+// readXcursor decodes the image closest to xcursorSize from an Xcursor file.
+// It returns the image with premultiplied ARGB pixels and its hotspot.
+func readXcursor(path string) (*image.RGBA, image.Point, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, image.Point{}, err
+	}
+	le := binary.LittleEndian
+	if len(data) < 16 || string(data[:4]) != xcursorMagic {
+		return nil, image.Point{}, fmt.Errorf("%s is not an Xcursor file", path)
+	}
+	ntoc := int64(le.Uint32(data[12:]))
+	tocEnd := 16 + ntoc*12
+	if tocEnd > int64(len(data)) {
+		return nil, image.Point{}, fmt.Errorf("%s: table of %d entries exceeds the file", path, ntoc)
+	}
+
+	// table of contents: type, subtype (nominal size), position
+	var pos uint32
+	best := -1
+	for i := 0; i < int(ntoc); i++ {
+		entry := 16 + i*12
+		if le.Uint32(data[entry:]) != xcursorImageType {
+			continue
+		}
+		size := int(le.Uint32(data[entry+4:]))
+		if best == -1 || abs(size-xcursorSize) < abs(best-xcursorSize) {
+			best = size
+			pos = le.Uint32(data[entry+8:])
+		}
+	}
+	if best == -1 {
+		return nil, image.Point{}, fmt.Errorf("%s contains no images", path)
+	}
+
+	// image chunk: header size, type, subtype, version,
+	// width, height, xhot, yhot, delay, pixels
+	if int64(pos) < tocEnd {
+		return nil, image.Point{}, fmt.Errorf("%s: image overlaps the table of contents", path)
+	}
+	if int(pos)+36 > len(data) {
+		return nil, image.Point{}, io.ErrUnexpectedEOF
+	}
+	chunk := data[pos:]
+	width := int64(le.Uint32(chunk[16:]))
+	height := int64(le.Uint32(chunk[20:]))
+	if width > xcursorMaxSize || height > xcursorMaxSize {
+		return nil, image.Point{}, fmt.Errorf("%s: image of %dx%d is too large", path, width, height)
+	}
+	hot := image.Pt(int(le.Uint32(chunk[24:])), int(le.Uint32(chunk[28:])))
+	pixels := chunk[36:]
+	if int64(len(pixels)) < width*height*4 {
+		return nil, image.Point{}, io.ErrUnexpectedEOF
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(width), int(height)))
+	for i := 0; i < int(width*height); i++ {
+		argb := le.Uint32(pixels[i*4:])
+		img.Pix[i*4+0] = uint8(argb >> 16)
+		img.Pix[i*4+1] = uint8(argb >> 8)
+		img.Pix[i*4+2] = uint8(argb)
+		img.Pix[i*4+3] = uint8(argb >> 24)
+	}
+	return img, hot, nil
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
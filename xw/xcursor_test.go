@@ -0,0 +1,112 @@
+package xw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// xcursorImage is an image chunk of an Xcursor file
+type xcursorImage struct {
+	size          uint32
+	width, height uint32
+	hot           image.Point
+	argb          uint32
+}
+
+// buildXcursor encodes the images with a table of contents
+func buildXcursor(images []xcursorImage) []byte {
+	var buf bytes.Buffer
+	u32 := func(v uint32) { binary.Write(&buf, binary.LittleEndian, v) }
+	buf.WriteString(xcursorMagic)
+	u32(16)
+	u32(0x10000)
+	u32(uint32(len(images)))
+	pos := uint32(16 + 12*len(images))
+	for _, img := range images {
+		u32(xcursorImageType)
+		u32(img.size)
+		u32(pos)
+		pos += 36 + 4*img.width*img.height
+	}
+	for _, img := range images {
+		for _, v := range []uint32{36, xcursorImageType, img.size, 1, img.width, img.height,
+			uint32(img.hot.X), uint32(img.hot.Y), 0} {
+			u32(v)
+		}
+		for i := uint32(0); i < img.width*img.height; i++ {
+			u32(img.argb)
+		}
+	}
+	return buf.Bytes()
+}
+
+func TestReadXcursor(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gllock")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	valid := buildXcursor([]xcursorImage{
+		{size: 16, width: 16, height: 16, hot: image.Pt(1, 2), argb: 0x11223344},
+		{size: 24, width: 24, height: 24, hot: image.Pt(3, 4), argb: 0x80ff0000},
+		{size: 48, width: 48, height: 48, hot: image.Pt(5, 6), argb: 0xff00ff00},
+	})
+	le := binary.LittleEndian
+	// modified returns a copy of valid with the uint32s at offsets set to v
+	modified := func(v uint32, offsets ...int) []byte {
+		data := append([]byte(nil), valid...)
+		for _, offset := range offsets {
+			le.PutUint32(data[offset:], v)
+		}
+		return data
+	}
+	// the image chunk of the 24px image follows the table and the 16px chunk
+	chunk24 := 16 + 3*12 + 36 + 4*16*16
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		err  bool
+	}{
+		{name: "valid", data: valid},
+		{name: "not an Xcursor file", data: []byte("PNG\x00not a cursor at all"), err: true},
+		{name: "truncated header", data: valid[:12], err: true},
+		// the table would reach into the pixels, which must not be read as entries
+		{name: "table beyond the file", data: modified(1000, 12), err: true},
+		{name: "table overflowing", data: modified(0xffffffff, 12), err: true},
+		{name: "no images in the table", data: modified(0xfffe0001, 16, 16+12, 16+24), err: true},
+		{name: "image beyond the file", data: modified(uint32(len(valid)), 16+12+8), err: true},
+		{name: "too large", data: modified(xcursorMaxSize+1, chunk24+16), err: true},
+		{name: "overflowing size", data: modified(0xffffffff, chunk24+20), err: true},
+		{name: "truncated pixels", data: valid[:chunk24+36+4*24], err: true},
+	} {
+		path := filepath.Join(dir, "cursor")
+		if err := ioutil.WriteFile(path, tc.data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		img, hot, err := readXcursor(path)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", tc.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", tc.name, err)
+			continue
+		}
+		// the image closest to xcursorSize is picked
+		if img.Bounds() != image.Rect(0, 0, 24, 24) || hot != image.Pt(3, 4) {
+			t.Errorf("%s: got %v with hotspot %v, want the 24px image", tc.name, img.Bounds(), hot)
+		}
+		if got := img.RGBAAt(0, 0); got.R != 0xff || got.G != 0 || got.B != 0 || got.A != 0x80 {
+			t.Errorf("%s: pixel is %v, want premultiplied red at half alpha", tc.name, got)
+		}
+	}
+}